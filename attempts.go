@@ -0,0 +1,54 @@
+package httpext
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// AttemptResponse is a response received by a single attempt. Body holds
+// at most the first respReadLimit bytes.
+type AttemptResponse struct {
+	StatusCode int
+	Headers    http.Header
+	Body       []byte
+	Duration   time.Duration
+}
+
+// DoDetailed is Do also returning, when CollectAllResponses is set, the
+// responses of every attempt in order, the final one included. Attempts
+// that failed without a response are left out.
+func (c *Client) DoDetailed(req *Request) (*http.Response, []AttemptResponse, error) {
+	if !c.CollectAllResponses {
+		resp, err := c.Do(req)
+		return resp, nil, err
+	}
+
+	var responses []AttemptResponse
+	req.responses = &responses
+	defer func() { req.responses = nil }()
+
+	resp, err := c.Do(req)
+	return resp, responses, err
+}
+
+// recordAttempt buffers the start of the body of resp for req.responses and
+// puts it back in front of the rest, so the body still reads in full.
+func (c *Client) recordAttempt(req *Request, resp *http.Response, start time.Time) {
+	// A read error is not reported here: the caller meets it again when
+	// reading past the buffered part.
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, respReadLimit))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+
+	*req.responses = append(*req.responses, AttemptResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Header.Clone(),
+		Body:       body,
+		Duration:   c.clock().Now().Sub(start),
+	})
+}
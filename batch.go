@@ -0,0 +1,153 @@
+package httpext
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// DoAll sends reqs concurrently, at most c.MaxConcurrency at a time (no limit
+// when zero), and discards the response bodies. The returned errors are in
+// the order of reqs; nil means a 2xx response was received.
+func (c *Client) DoAll(ctx context.Context, reqs []*Request) []error {
+	errs := make([]error, len(reqs))
+
+	var sem chan struct{}
+	if c.MaxConcurrency > 0 {
+		sem = make(chan struct{}, c.MaxConcurrency)
+	}
+
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		if sem != nil {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				for j := i; j < len(reqs); j++ {
+					errs[j] = ctx.Err()
+				}
+				wg.Wait()
+				return errs
+			}
+		}
+
+		wg.Add(1)
+		go func(i int, req *Request) {
+			defer wg.Done()
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+			errs[i] = c.doDiscard(ctx, req)
+		}(i, req)
+	}
+	wg.Wait()
+	return errs
+}
+
+func (c *Client) doDiscard(ctx context.Context, req *Request) error {
+	withCtx := *req
+	withCtx.Request = req.Request.WithContext(ctx)
+
+	resp, err := c.Do(&withCtx)
+	if err != nil {
+		return err
+	}
+	if err := c.EnsureSuccess(resp); err != nil {
+		return err
+	}
+	c.drainBody(ctx, resp.Body, nil)
+	return nil
+}
+
+type multiResult struct {
+	index int
+	resp  *http.Response
+	err   error
+}
+
+// MultiDo sends a copy of req to each of urls concurrently and returns the
+// first response that Do returns without an error; the other requests are
+// cancelled. The body of req is buffered so that each copy can replay it.
+// When no URL succeeds, the error of the last one to fail is returned.
+// Middleware added with Request.Use runs for every copy. Requests built with
+// NonRetryableRequest or NewRequestWithProvider cannot be copied and are
+// rejected.
+func (c *Client) MultiDo(req *Request, urls []string) (*http.Response, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("MultiDo: no URLs given")
+	}
+	if req.noRetry {
+		return nil, errors.New("MultiDo: a non-retryable request body cannot be replayed")
+	}
+	if req.provider != nil {
+		return nil, errors.New("MultiDo: requests with a BodyProvider are not supported")
+	}
+
+	body, err := peekBody(req, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	clones := make([]*Request, len(urls))
+	cancels := make([]context.CancelFunc, len(urls))
+	for i, u := range urls {
+		var rs io.ReadSeeker
+		if req.body != nil {
+			rs = bytes.NewReader(body)
+		}
+		clone, err := NewRequest(req.Method, u, rs)
+		if err != nil {
+			return nil, err
+		}
+
+		var ctx context.Context
+		ctx, cancels[i] = context.WithCancel(req.Context())
+		clone.Request = clone.Request.WithContext(ctx)
+		clone.Header = req.Header.Clone()
+		clone.Host = req.Host
+		clone.Timeout = req.Timeout
+		clone.middleware = append([]Middleware(nil), req.middleware...)
+		clones[i] = clone
+	}
+
+	results := make(chan multiResult, len(urls))
+	for i, clone := range clones {
+		go func(i int, clone *Request) {
+			resp, err := c.Do(clone)
+			results <- multiResult{index: i, resp: resp, err: err}
+		}(i, clone)
+	}
+
+	var lastErr error
+	for pending := len(urls); pending > 0; pending-- {
+		res := <-results
+		if res.err != nil {
+			lastErr = res.err
+			cancels[res.index]()
+			continue
+		}
+
+		for i, cancel := range cancels {
+			if i != res.index {
+				cancel()
+			}
+		}
+		go closeResponses(results, pending-1)
+		res.resp.Body = &cancelOnClose{ReadCloser: res.resp.Body, cancel: cancels[res.index]}
+		return res.resp, nil
+	}
+	return nil, lastErr
+}
+
+// closeResponses releases the responses of the MultiDo requests that lost
+// the race.
+func closeResponses(results <-chan multiResult, n int) {
+	for ; n > 0; n-- {
+		if res := <-results; res.resp != nil {
+			res.resp.Body.Close()
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package httpext
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMultiDoAppliesRequestMiddleware(t *testing.T) {
+	bodies := make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies <- string(body)
+	}))
+	defer srv.Close()
+
+	req, err := NewRequest("POST", srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Use(EncodeJSON(map[string]int{"a": 1}))
+
+	resp, err := newTestClient(srv, 0).MultiDo(req, []string{srv.URL + "/1", srv.URL + "/2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := <-bodies; got != `{"a":1}` {
+		t.Errorf("server got body %q, want %q", got, `{"a":1}`)
+	}
+}
+
+func TestMultiDoRejectsNonRetryableRequests(t *testing.T) {
+	req, err := NonRetryableRequest("POST", "http://example.test/", bytes.NewBufferString("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClient(nil).MultiDo(req, []string{"http://example.test/"}); err == nil {
+		t.Error("got no error for a non-retryable request")
+	}
+}
+
+func TestMultiDoAcceptsWhatDoAccepts(t *testing.T) {
+	srv, _ := statusServer(t, http.StatusServiceUnavailable)
+	c := newTestClient(srv, 2)
+	c.ExtendedCheckForRetry = func(attempt int, resp *http.Response, err error) (bool, error) {
+		return attempt == 0, err
+	}
+
+	req, err := NewRequest("GET", srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.MultiDo(req, []string{srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status %d, want the 503 accepted by the policy", resp.StatusCode)
+	}
+}
+
+func TestMultiDoRedactsURLsInErrors(t *testing.T) {
+	srv, _ := statusServer(t, http.StatusServiceUnavailable)
+	c := newTestClient(srv, 0)
+	c.SanitizeQueryParams = []string{"token"}
+
+	req, err := NewRequest("GET", srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.MultiDo(req, []string{srv.URL + "/?token=SECRET"})
+	if err == nil {
+		t.Fatal("got no error, want the 503 to fail the request")
+	}
+	if strings.Contains(err.Error(), "SECRET") {
+		t.Errorf("error leaks a sanitized query parameter: %v", err)
+	}
+}
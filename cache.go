@@ -0,0 +1,170 @@
+package httpext
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStore keeps successful GET responses. Implementations must be safe for
+// concurrent use. Responses handed to Set carry a fully buffered body, and
+// every cache hit is served with its own copy of that body as long as the
+// store hands back the very response it was given.
+type CacheStore interface {
+	Get(key string) (*http.Response, bool)
+	Set(key string, resp *http.Response, ttl time.Duration)
+}
+
+// doCached serves GET requests from c.Cache. Keys are the full request URL
+// followed by the values of c.CacheKeyHeaders and the credential hashes. Only complete 200 responses
+// are stored: Range requests bypass the cache, and responses marked
+// Cache-Control: no-store are not kept.
+func (c *Client) doCached(req *Request) (*http.Response, error) {
+	if req.Header.Get("Range") != "" {
+		return c.doGET(req)
+	}
+
+	key := c.cacheKey(req)
+	if cached, ok := c.Cache.Get(key); ok {
+		return copyBufferedResponse(cached), nil
+	}
+
+	resp, err := c.doGET(req)
+	if err != nil || resp.StatusCode != http.StatusOK || ParseCacheControl(resp).NoStore {
+		return resp, err
+	}
+
+	if err := bufferResponse(resp); err != nil {
+		return nil, err
+	}
+	c.Cache.Set(key, copyBufferedResponse(resp), c.CacheTTL)
+	return resp, nil
+}
+
+// credentialHeaders always take part in cache keys, hashed so that stores
+// never see the credentials themselves.
+var credentialHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization"}
+
+func (c *Client) cacheKey(req *Request) string {
+	var key strings.Builder
+	key.WriteString(req.URL.String())
+	for _, name := range c.CacheKeyHeaders {
+		key.WriteString("\n")
+		key.WriteString(http.CanonicalHeaderKey(name))
+		key.WriteString(": ")
+		key.WriteString(strings.Join(req.Header.Values(name), ", "))
+	}
+	for _, name := range credentialHeaders {
+		if values := req.Header.Values(name); len(values) > 0 {
+			key.WriteString("\n")
+			key.WriteString(name)
+			key.WriteString(": sha256=")
+			key.WriteString(sha256Hex([]byte(strings.Join(values, "\n"))))
+		}
+	}
+	return key.String()
+}
+
+// MemoryCache is an in-process CacheStore. A non-positive ttl keeps the
+// entry until it is overwritten.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryCacheEntry
+}
+
+type memoryCacheEntry struct {
+	resp    *http.Response
+	expires time.Time
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]memoryCacheEntry)}
+}
+
+func (m *MemoryCache) Get(key string) (*http.Response, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		delete(m.entries, key)
+		return nil, false
+	}
+	return entry.resp, true
+}
+
+func (m *MemoryCache) Set(key string, resp *http.Response, ttl time.Duration) {
+	entry := memoryCacheEntry{resp: resp}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = entry
+}
+
+// CacheControlDirectives holds the Cache-Control response directives of
+// RFC 7234. MaxAge and SMaxAge are nil when absent or malformed.
+type CacheControlDirectives struct {
+	MaxAge          *time.Duration
+	SMaxAge         *time.Duration
+	NoCache         bool
+	NoStore         bool
+	NoTransform     bool
+	MustRevalidate  bool
+	ProxyRevalidate bool
+	Private         bool
+	Public          bool
+	Immutable       bool
+}
+
+func ParseCacheControl(resp *http.Response) CacheControlDirectives {
+	var cc CacheControlDirectives
+	for _, header := range resp.Header.Values("Cache-Control") {
+		for _, directive := range strings.Split(header, ",") {
+			name, value := directive, ""
+			if i := strings.IndexByte(directive, '='); i >= 0 {
+				name, value = directive[:i], strings.Trim(strings.TrimSpace(directive[i+1:]), `"`)
+			}
+
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "max-age":
+				cc.MaxAge = parseDeltaSeconds(value)
+			case "s-maxage":
+				cc.SMaxAge = parseDeltaSeconds(value)
+			case "no-cache":
+				cc.NoCache = true
+			case "no-store":
+				cc.NoStore = true
+			case "no-transform":
+				cc.NoTransform = true
+			case "must-revalidate":
+				cc.MustRevalidate = true
+			case "proxy-revalidate":
+				cc.ProxyRevalidate = true
+			case "private":
+				cc.Private = true
+			case "public":
+				cc.Public = true
+			case "immutable":
+				cc.Immutable = true
+			}
+		}
+	}
+	return cc
+}
+
+func parseDeltaSeconds(value string) *time.Duration {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return nil
+	}
+	d := time.Duration(seconds) * time.Second
+	return &d
+}
@@ -0,0 +1,66 @@
+package httpext
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheSkipsPartialResponses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "data", time.Time{}, strings.NewReader("0123456789"))
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv, 0)
+	c.Cache = NewMemoryCache()
+
+	req, err := NewRequest("GET", srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Range", "bytes=5-")
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Fatalf("Range request: status %d, want 206", resp.StatusCode)
+	}
+
+	resp, err = c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "0123456789" {
+		t.Errorf("plain GET: got %d %q, want 200 %q", resp.StatusCode, body, "0123456789")
+	}
+}
+
+func TestCacheHonoursNoStore(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Cache-Control", "no-store")
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv, 0)
+	c.Cache = NewMemoryCache()
+	for i := 0; i < 2; i++ {
+		resp, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if hits != 2 {
+		t.Errorf("server got %d requests, want 2", hits)
+	}
+}
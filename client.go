@@ -1,11 +1,25 @@
 package httpext
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
+	"math/rand"
+	"net"
 	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
 var (
@@ -15,17 +29,193 @@ var (
 )
 
 type Client struct {
+	// Metrics is kept first so that its counters stay 64-bit aligned for
+	// sync/atomic on 32-bit platforms.
+	Metrics Metrics
+
 	HTTPClient   *http.Client
 	RetryWaitMin time.Duration
 	RetryWaitMax time.Duration
-	RetriesMax   int
+	// RetriesMax is the number of retries after the first attempt, which is
+	// always made: 0 sends the request exactly once, 1 allows one retry
+	// (two attempts in total), and so on.
+	RetriesMax int
+
+	PerAttemptTimeout time.Duration
+	// CancelAfterAttempt, when positive, shortens PerAttemptTimeout for the
+	// attempts that follow the first CancelAfterAttempt ones, multiplying it
+	// by CancelAfterAttemptFactor (0.5 by default).
+	CancelAfterAttempt       int
+	CancelAfterAttemptFactor float64
+
+	// OverallTimeout bounds each Do call as a whole: every attempt and every
+	// backoff sleep. Do fails with context.DeadlineExceeded once it elapses.
+	OverallTimeout time.Duration
+
+	// ConnectTimeout limits dialing, TLSHandshakeTimeout the TLS handshake
+	// and ResponseHeaderTimeout the wait for response headers once the
+	// request is written, leaving HTTPClient.Timeout for the whole exchange.
+	// They apply within each attempt, so PerAttemptTimeout still caps them
+	// all. Transport settings are read on the first request.
+	ConnectTimeout        time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	// DisableCompression stops the transport from asking for gzip and
+	// decompressing responses behind the caller's back.
+	DisableCompression bool
+	transportOnce      sync.Once
+	derivedClient      *http.Client
 
 	CheckForRetry CheckForRetry
 	Backoff       Backoff
+
+	// ExtendedCheckForRetry takes precedence over CheckForRetry when set.
+	ExtendedCheckForRetry ExtendedCheckForRetry
+
+	// RetryOnDNSFailure retries temporary DNS resolution errors whatever
+	// the retry policy decides.
+	RetryOnDNSFailure bool
+
+	// digestAuth, installed by WithDigestAuth, retries Digest challenges
+	// whatever the retry policy decides.
+	digestAuth *digestAuth
+
+	// HealthCheckURL is polled every HealthCheckInterval (10s by default)
+	// from the first Do call on. While it does not answer with a 2xx status,
+	// Do stops retrying and fails with ErrTargetUnhealthy instead. The
+	// poller runs until StopHealthCheck or GracefulShutdown is called.
+	HealthCheckURL      string
+	HealthCheckInterval time.Duration
+	unhealthy           int32
+	healthOnce          sync.Once
+	healthStop          chan struct{}
+	healthDone          chan struct{}
+	healthStopOnce      sync.Once
+
+	// Cache keys GET responses by URL, CacheKeyHeaders values and a hash of
+	// the Authorization, Proxy-Authorization and Cookie headers, so that
+	// responses are only shared between callers with the same credentials.
+	Cache           CacheStore
+	CacheTTL        time.Duration
+	CacheKeyHeaders []string
+
+	// StatusErrorMap maps status codes to errors wrapped by the HTTPError
+	// that Client.EnsureSuccess returns.
+	StatusErrorMap map[int]error
+
+	// ValidateContentLength makes Do fail before sending when the request
+	// declares a Content-Length that differs from the actual body size.
+	ValidateContentLength bool
+
+	// MaxBodyRetrySizeBytes stops Do from resending *bytes.Reader bodies
+	// larger than this; the first attempt is always sent.
+	MaxBodyRetrySizeBytes int64
+
+	// PreflightHEAD sends a HEAD request with the same headers before any
+	// request whose body is a *bytes.Reader, and returns the HEAD response
+	// instead of uploading the body when it carries an error status.
+	PreflightHEAD bool
+
+	// Interceptor, when set, replaces HTTPClient for every attempt, which
+	// lets tests serve canned responses while keeping the retry logic.
+	Interceptor Interceptor
+
+	// Middleware runs once per Do call, after the request's own middleware.
+	Middleware []Middleware
+	// RequestHooks run before every attempt, retries included.
+	RequestHooks []RequestHook
+
+	MaxConcurrency int
+
+	// DeduplicateGET coalesces concurrent GET requests into a single
+	// in-flight request. It uses the Cache key, so requests carrying
+	// different credentials are never merged.
+	DeduplicateGET bool
+	getGroup       singleflight.Group
+
+	UserAgent          string
+	DefaultHeaders     http.Header
+	DefaultQueryParams url.Values
+
+	// SanitizeQueryParams lists query parameters whose values are replaced
+	// with [REDACTED] in log output and in errors that include the URL.
+	SanitizeQueryParams []string
+
+	// NormalizeURL cleans request paths, e.g. /a//b/../c becomes /a/c, and
+	// re-encodes them canonically. It changes the request for APIs that
+	// rely on a particular raw path encoding, such as encoded slashes.
+	NormalizeURL bool
+
+	// HostOverride replaces the Host header of every request, while the
+	// connection is still made to the host in the request URL.
+	HostOverride string
+
+	// CorrelationIDHeader names the header that carries the correlation ID
+	// stored in the request context by ContextWithCorrelationID.
+	CorrelationIDHeader string
+
+	// RetryIdempotentOnly only retries GET, HEAD, OPTIONS, TRACE, PUT and
+	// DELETE, the idempotent methods of RFC 7231.
+	RetryIdempotentOnly bool
+
+	// ResponseBodyTransform may replace resp.Body, e.g. with a decrypting
+	// reader, after every attempt that got a response and before
+	// CheckForRetry sees it. An error is handed to CheckForRetry the same way
+	// as a transport error.
+	ResponseBodyTransform func(resp *http.Response) error
+
+	// ResponseSignatureVerifier checks the fully read body of the final
+	// response. A failure is not retried: Do returns a
+	// *ResponseVerificationError instead of the response.
+	ResponseSignatureVerifier func(resp *http.Response, body []byte) error
+
+	// CaptureTrailers reads the final response body before Do returns so
+	// that resp.Trailer holds the trailers sent after it.
+	CaptureTrailers bool
+
+	// CollectAllResponses makes DoDetailed report every response received,
+	// retried ones included.
+	CollectAllResponses bool
+
+	// TraceContext wraps the context of every attempt, typically with
+	// httptrace.WithClientTrace to time DNS, connection and TLS setup per
+	// attempt. GetAttemptNumber already works on the context it receives.
+	TraceContext func(ctx context.Context) context.Context
+
+	// DebugLogger, when set, receives up to DebugBodyLimit bytes (512 by
+	// default) of every request body sent and of the returned response body.
+	DebugLogger    Logger
+	DebugBodyLimit int
+
+	shutdownMu   sync.Mutex
+	shuttingDown bool
+	inFlight     sync.WaitGroup
+
+	// Clock defaults to the system clock. Tests may replace it to observe
+	// backoff without actually sleeping.
+	Clock Clock
+}
+
+type Option func(c *Client)
+
+type Interceptor interface {
+	Intercept(req *Request) (*http.Response, error)
 }
 
-func NewClient(client *http.Client) *Client {
-	return &Client{
+// NewClient wraps client, which is used as is rather than copied, so its
+// Transport, Timeout, CheckRedirect and Jar all keep applying. When a
+// transport setting of the Client is used, requests go through a copy of
+// client that differs only by its cloned Transport.
+func NewClient(client *http.Client, opts ...Option) *Client {
+	c := newClient(client, opts)
+	if err := c.Validate(); err != nil {
+		panic("httpext: " + err.Error())
+	}
+	return c
+}
+
+func newClient(client *http.Client, opts []Option) *Client {
+	c := &Client{
 		HTTPClient:    client,
 		RetryWaitMin:  defaultRetryWaitMin,
 		RetryWaitMax:  defaultRetryWaitMax,
@@ -33,8 +223,31 @@ func NewClient(client *http.Client) *Client {
 		CheckForRetry: DefaultRetryPolicy,
 		Backoff:       DefaultBackoff,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
+func (c *Client) Validate() error {
+	if c.RetryWaitMin > c.RetryWaitMax {
+		return fmt.Errorf("RetryWaitMin (%s) is greater than RetryWaitMax (%s)", c.RetryWaitMin, c.RetryWaitMax)
+	}
+	if c.RetriesMax < 0 {
+		return fmt.Errorf("RetriesMax (%d) is negative", c.RetriesMax)
+	}
+	if c.CheckForRetry == nil && c.ExtendedCheckForRetry == nil {
+		return errors.New("CheckForRetry is nil")
+	}
+	if c.Backoff == nil {
+		return errors.New("Backoff is nil")
+	}
+	return nil
+}
+
+// CheckForRetry decides whether Do retries after an attempt. An error
+// returned for a response that is not retried makes Do release the
+// response and fail with that error instead.
 type CheckForRetry func(resp *http.Response, err error) (bool, error)
 
 func DefaultRetryPolicy(resp *http.Response, err error) (bool, error) {
@@ -42,13 +255,112 @@ func DefaultRetryPolicy(resp *http.Response, err error) (bool, error) {
 		return true, err
 	}
 
-	if resp.StatusCode == 0 || resp.StatusCode >= 500 {
+	switch {
+	case resp.StatusCode == 0 || resp.StatusCode >= 500:
+		// Most notably 502 Bad Gateway, 503 Service Unavailable and
+		// 504 Gateway Timeout, which load balancers return while backends
+		// restart during rolling deployments.
+		return true, nil
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests:
 		return true, nil
 	}
 
 	return false, nil
 }
 
+// ExtendedCheckForRetry is a CheckForRetry that also receives the zero-based
+// number of the attempt that produced resp and err.
+type ExtendedCheckForRetry func(attempt int, resp *http.Response, err error) (bool, error)
+
+// ExtendCheckForRetry adapts check to ExtendedCheckForRetry by ignoring the
+// attempt number.
+func ExtendCheckForRetry(check CheckForRetry) ExtendedCheckForRetry {
+	return func(_ int, resp *http.Response, err error) (bool, error) {
+		return check(resp, err)
+	}
+}
+
+// NeverRetryPolicy disables retries while keeping RetriesMax untouched.
+func NeverRetryPolicy(resp *http.Response, err error) (bool, error) {
+	return false, nil
+}
+
+// AlwaysRetryPolicy retries every attempt until RetriesMax is exhausted,
+// so it must be paired with a finite RetriesMax.
+func AlwaysRetryPolicy(resp *http.Response, err error) (bool, error) {
+	return true, nil
+}
+
+// RetryAll retries only when every policy agrees to retry. The first policy
+// that returns an error of its own short-circuits the chain with its result;
+// policies such as DefaultRetryPolicy that merely hand the request error
+// back do not, so the remaining policies still get a say.
+func RetryAll(policies ...CheckForRetry) CheckForRetry {
+	return func(resp *http.Response, err error) (bool, error) {
+		for _, policy := range policies {
+			retry, checkErr := policy(resp, err)
+			if checkErr != nil && !sameError(checkErr, err) {
+				return retry, checkErr
+			}
+			if !retry {
+				return false, nil
+			}
+		}
+		return len(policies) > 0, nil
+	}
+}
+
+// RetryAny retries when at least one policy agrees to retry. Errors are
+// handled as in RetryAll.
+func RetryAny(policies ...CheckForRetry) CheckForRetry {
+	return func(resp *http.Response, err error) (bool, error) {
+		for _, policy := range policies {
+			retry, checkErr := policy(resp, err)
+			if checkErr != nil && !sameError(checkErr, err) {
+				return retry, checkErr
+			}
+			if retry {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// sameError reports whether a policy returned the request error unchanged.
+func sameError(checkErr, err error) bool {
+	if err == nil || !reflect.TypeOf(checkErr).Comparable() {
+		return false
+	}
+	return checkErr == err
+}
+
+// ErrorTypeRetryPolicy retries only when the request error matches one of
+// errTypes. Non-nil values are matched with errors.Is; typed nil pointers,
+// e.g. (*net.DNSError)(nil), match any error of that type with errors.As.
+// Responses received without an error are never retried.
+func ErrorTypeRetryPolicy(errTypes ...error) CheckForRetry {
+	return func(resp *http.Response, err error) (bool, error) {
+		if err == nil {
+			return false, nil
+		}
+		for _, errType := range errTypes {
+			if matchError(err, errType) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+func matchError(err, target error) bool {
+	v := reflect.ValueOf(target)
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return errors.As(err, reflect.New(v.Type()).Interface())
+	}
+	return errors.Is(err, target)
+}
+
 type Backoff func(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration
 
 func DefaultBackoff(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
@@ -59,62 +371,608 @@ func DefaultBackoff(min, max time.Duration, attemptNum int, resp *http.Response)
 	return sleep
 }
 
+// FullJitterBackoff picks a random wait in [0, min*base^attemptNum], capped
+// at max. Unlike DefaultBackoff, clients that failed at the same moment do
+// not retry in lockstep, which spreads the load on a recovering server
+// instead of hitting it with synchronized bursts.
+func FullJitterBackoff(base float64) Backoff {
+	return func(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+		ceiling := float64(min) * math.Pow(base, float64(attemptNum))
+		if ceiling > float64(max) || math.IsInf(ceiling, 0) || math.IsNaN(ceiling) {
+			ceiling = float64(max)
+		}
+		if ceiling <= 0 {
+			return 0
+		}
+		// The top-level math/rand functions are safe for concurrent use.
+		return time.Duration(rand.Int63n(int64(ceiling) + 1))
+	}
+}
+
 type Request struct {
-	body io.ReadSeeker
+	body       io.ReadSeeker
+	noRetry    bool
+	middleware []Middleware
+	provider   BodyProvider
+	responses  *[]AttemptResponse
 	*http.Request
+
+	// Timeout bounds the whole Do call, retries and backoff included, when
+	// the request context has no deadline of its own. It is independent of
+	// Client.PerAttemptTimeout: whichever expires first wins.
+	Timeout time.Duration
 }
 
+// NewRequest accepts any valid HTTP token as method, so extension methods
+// such as PURGE or PROPFIND work as well as the standard ones.
 func NewRequest(method, url string, body io.ReadSeeker) (*Request, error) {
 	httpReq, err := http.NewRequest(method, url, body)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Request{
+	req := &Request{
 		body:    body,
 		Request: httpReq,
+	}
+	if body != nil {
+		httpReq.GetBody = req.getBody
+	}
+	return req, nil
+}
+
+// NonRetryableRequest streams body to the server as is, without buffering it.
+// Since such a body can be read only once, the request is sent exactly one
+// time regardless of RetriesMax and CheckForRetry.
+func NonRetryableRequest(method, url string, body io.Reader) (*Request, error) {
+	httpReq, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Request{
+		noRetry: true,
+		Request: httpReq,
 	}, nil
 }
 
+// NewRequestFromChan collects every chunk received from chunks into a
+// retryable body. It blocks until the channel is closed, so it does not
+// suit endless streams; use NonRetryableRequest for those.
+func NewRequestFromChan(method, url string, chunks <-chan []byte) (*Request, error) {
+	var buf bytes.Buffer
+	for chunk := range chunks {
+		buf.Write(chunk)
+	}
+	return NewRequest(method, url, bytes.NewReader(buf.Bytes()))
+}
+
+// BodyProvider builds a fresh request body for every attempt, e.g. when
+// the body embeds a token that may be refreshed between retries.
+type BodyProvider interface {
+	NewBody() (io.ReadSeeker, error)
+}
+
+// NewRequestWithProvider is NewRequest with a body obtained from provider
+// before each attempt instead of rewinding a single body.
+func NewRequestWithProvider(method, url string, provider BodyProvider) (*Request, error) {
+	httpReq, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Request{
+		provider: provider,
+		Request:  httpReq,
+	}, nil
+}
+
+func (r *Request) provideBody() error {
+	body, err := r.provider.NewBody()
+	if err != nil {
+		return err
+	}
+	size, err := body.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	r.setBody(body, size)
+	return nil
+}
+
+// getBody rewinds the body so that net/http can replay it, e.g. when
+// following a 307/308 redirect.
+func (r *Request) getBody() (io.ReadCloser, error) {
+	if _, err := r.body.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(r.body), nil
+}
+
+func (r *Request) WithHeaders(headers map[string]string) *Request {
+	for key, value := range headers {
+		r.Header.Set(key, value)
+	}
+	return r
+}
+
+func (r *Request) AddHeaders(headers map[string][]string) *Request {
+	for key, values := range headers {
+		for _, value := range values {
+			r.Header.Add(key, value)
+		}
+	}
+	return r
+}
+
+// CommonSecurityHeaders are sensible defaults for WithSecurityHeaders or
+// Client.DefaultHeaders. Copy the map before changing it.
+var CommonSecurityHeaders = map[string]string{
+	"X-Content-Type-Options": "nosniff",
+	"X-Frame-Options":        "DENY",
+	"Referrer-Policy":        "no-referrer",
+}
+
+func (r *Request) WithSecurityHeaders(headers map[string]string) *Request {
+	return r.WithHeaders(headers)
+}
+
+func (r *Request) SetContentType(ct string) *Request {
+	r.Header.Set("Content-Type", ct)
+	return r
+}
+
+// WithBodyHash returns the hex-encoded SHA-256 of the request body, leaving
+// the body rewound, so that the exact payload can be logged before Do. It
+// fails with ErrBodyNotReplayable for bodies it cannot read ahead of Do.
+func WithBodyHash(req *Request) (string, error) {
+	body, err := peekBody(req, -1)
+	if err != nil {
+		return "", err
+	}
+	return sha256Hex(body), nil
+}
+
 func (c *Client) Do(req *Request) (*http.Response, error) {
+	if !c.enter() {
+		return nil, ErrShuttingDown
+	}
+	defer c.inFlight.Done()
+
+	if c.HealthCheckURL != "" {
+		c.startHealthCheck()
+	}
+	c.prepare(req)
+	atomic.AddInt64(&c.Metrics.TotalRequests, 1)
+
+	resp, err := c.dispatch(req)
+	if err != nil {
+		atomic.AddInt64(&c.Metrics.TotalFailures, 1)
+		err = c.redactError(err)
+	}
+	return resp, err
+}
+
+func (c *Client) dispatch(req *Request) (*http.Response, error) {
+	if err := c.applyMiddleware(req); err != nil {
+		return nil, err
+	}
+	if c.PreflightHEAD {
+		if resp, done, err := c.preflight(req); done {
+			return resp, err
+		}
+	}
+
+	if req.Method == "GET" {
+		if c.Cache != nil {
+			return c.doCached(req)
+		}
+		return c.doGET(req)
+	}
+	return c.do(req)
+}
+
+func (c *Client) do(req *Request) (*http.Response, error) {
+	ctx, cancel := c.requestContext(req)
+
+	resp, err := c.retry(ctx, req)
+	if err == nil && c.ResponseSignatureVerifier != nil {
+		resp, err = c.verifyResponse(resp)
+	}
+	if err == nil && c.CaptureTrailers {
+		resp, err = captureTrailers(resp)
+	}
+	if resp == nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, err
+}
+
+func (c *Client) retry(ctx context.Context, req *Request) (*http.Response, error) {
+	retriesMax := c.RetriesMax
+	if req.noRetry || c.RetryIdempotentOnly && !isIdempotent(req.Method) {
+		retriesMax = 0
+	}
+
+	if c.ValidateContentLength {
+		if err := validateContentLength(req); err != nil {
+			return nil, err
+		}
+	}
+
+	var lastResp *http.Response
 	for i := 0; ; i++ {
-		if req.body != nil {
+		if req.provider != nil {
+			if err := req.provideBody(); err != nil {
+				return nil, err
+			}
+		} else if req.body != nil {
 			if _, err := req.body.Seek(0, io.SeekStart); err != nil {
+				return nil, &SeekError{Attempt: i, URL: c.redactURL(req.URL), Cause: err}
+			}
+		}
+
+		attempt, attemptCancel := c.attemptRequest(ctx, req, i)
+		for _, hook := range c.RequestHooks {
+			if err := hook(attempt); err != nil {
+				attemptCancel()
 				return nil, err
 			}
 		}
+		if c.DebugLogger != nil {
+			c.debugRequest(attempt, i)
+		}
 
-		resp, err := c.HTTPClient.Do(req.Request)
+		start := c.clock().Now()
+		resp, err := c.send(attempt)
+		if err == nil && c.ResponseBodyTransform != nil {
+			if transformErr := c.ResponseBodyTransform(resp); transformErr != nil {
+				resp.Body.Close()
+				resp, err = nil, transformErr
+			}
+		}
+		if req.responses != nil && resp != nil {
+			c.recordAttempt(req, resp, start)
+		}
 
-		needRetry, checkErr := c.CheckForRetry(resp, err)
+		needRetry, checkErr := c.checkForRetry(i, resp, err)
 		if !needRetry {
 			if checkErr != nil {
 				err = checkErr
 			}
-			return resp, err
+			if resp == nil {
+				attemptCancel()
+				return nil, err
+			}
+			if err != nil {
+				// The policy, or the health check, rejected the response:
+				// release it rather than leaving it to callers that only
+				// look at err.
+				c.drainBody(ctx, resp.Body, attemptCancel)
+				attemptCancel()
+				return nil, fmt.Errorf("%w (last response: %s)", err, resp.Status)
+			}
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: attemptCancel}
+			if c.DebugLogger != nil {
+				c.debugResponse(resp)
+			}
+			return resp, nil
 		}
 
 		if err == nil {
-			c.drainBody(resp.Body)
+			c.drainBody(ctx, resp.Body, attemptCancel)
+		}
+		attemptCancel()
+		if resp != nil {
+			lastResp = resp
+		}
+		// The deadline or cancellation of the whole call, rather than the
+		// retry budget, ended this attempt.
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
 
-		if remain := c.RetriesMax - i; remain == 0 {
+		if remain := retriesMax - i; remain == 0 {
 			break
 		}
+		if c.bodyTooLargeToRetry(req) {
+			return nil, fmt.Errorf("%s %s: %w", req.Method, c.redactURL(req.URL), ErrBodyTooLargeToRetry)
+		}
 
+		atomic.AddInt64(&c.Metrics.TotalRetries, 1)
 		wait := c.Backoff(c.RetryWaitMin, c.RetryWaitMax, i, resp)
-		time.Sleep(wait)
+		if err := c.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+	return nil, &MaxRetriesExceededError{
+		Method:       req.Method,
+		URL:          c.redactURL(req.URL),
+		Attempts:     retriesMax + 1,
+		LastResponse: lastResp,
 	}
-	return nil, fmt.Errorf("%s %s giving up after %d attempts", req.Method, req.URL, c.RetriesMax)
 }
 
-const respReadLimit = 1 << 20 // 1 Мб
+var ErrBodyTooLargeToRetry = errors.New("request body too large to retry")
 
-func (c *Client) drainBody(body io.ReadCloser) {
+func (c *Client) bodyTooLargeToRetry(req *Request) bool {
+	if c.MaxBodyRetrySizeBytes <= 0 {
+		return false
+	}
+	body, ok := req.body.(*bytes.Reader)
+	return ok && body.Size() > c.MaxBodyRetrySizeBytes
+}
+
+var ErrContentLengthMismatch = errors.New("body size does not match Content-Length")
+
+// validateContentLength only checks seekable bodies: the size of streamed
+// or provided bodies is not known ahead of the attempt.
+func validateContentLength(req *Request) error {
+	if req.ContentLength <= 0 || req.body == nil {
+		return nil
+	}
+
+	size, err := req.body.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := req.body.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if size != req.ContentLength {
+		return fmt.Errorf("%w: body is %d bytes, Content-Length is %d", ErrContentLengthMismatch, size, req.ContentLength)
+	}
+	return nil
+}
+
+// normalizeURL cleans duplicate slashes and dot segments from the path,
+// keeping a trailing slash, and re-encodes it in its canonical form.
+func normalizeURL(u *url.URL) {
+	if u.Path == "" {
+		return
+	}
+	cleaned := path.Clean(u.Path)
+	if strings.HasSuffix(u.Path, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	u.Path = cleaned
+	u.RawPath = ""
+}
+
+// prepare fills in the headers and query parameters the Client adds to
+// every request without overriding the ones set by the caller, except for
+// HostOverride which always wins.
+func (c *Client) prepare(req *Request) {
+	if c.NormalizeURL {
+		normalizeURL(req.URL)
+	}
+	if c.HostOverride != "" {
+		req.Host = c.HostOverride
+	}
+	if len(c.DefaultQueryParams) > 0 {
+		// Only the missing keys are appended: the existing query is left as
+		// is, so that signed or order-sensitive URLs survive.
+		query, _ := url.ParseQuery(req.URL.RawQuery)
+		missing := make(url.Values)
+		for key, values := range c.DefaultQueryParams {
+			if _, ok := query[key]; !ok {
+				missing[key] = values
+			}
+		}
+		if len(missing) > 0 {
+			if req.URL.RawQuery != "" {
+				req.URL.RawQuery += "&"
+			}
+			req.URL.RawQuery += missing.Encode()
+		}
+	}
+	for key, values := range c.DefaultHeaders {
+		key = http.CanonicalHeaderKey(key)
+		if _, ok := req.Header[key]; !ok {
+			req.Header[key] = append([]string(nil), values...)
+		}
+	}
+	if c.UserAgent != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	if c.CorrelationIDHeader != "" && req.Header.Get(c.CorrelationIDHeader) == "" {
+		if id, ok := CorrelationID(req.Context()); ok {
+			req.Header.Set(c.CorrelationIDHeader, id)
+		}
+	}
+}
+
+// isIdempotent reports whether method is one of the idempotent methods of
+// RFC 7231 section 4.2.2. Extension methods such as LOCK are assumed not to
+// be, and method names are case-sensitive.
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+func (c *Client) send(req *Request) (*http.Response, error) {
+	if c.Interceptor != nil {
+		return c.Interceptor.Intercept(req)
+	}
+	return c.httpClient().Do(req.Request)
+}
+
+// attemptRequest returns a shallow copy of req bound to the context of the
+// given attempt, which carries the attempt number and PerAttemptTimeout.
+// The context can always be cancelled, e.g. to abort draining its body.
+func (c *Client) attemptRequest(ctx context.Context, req *Request, attempt int) (*Request, context.CancelFunc) {
+	var cancel context.CancelFunc
+	if timeout := c.attemptTimeout(attempt); timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	ctx = context.WithValue(ctx, attemptKey{}, attempt)
+	if c.TraceContext != nil {
+		ctx = c.TraceContext(ctx)
+	}
+
+	dup := *req
+	dup.Request = req.Request.WithContext(ctx)
+	return &dup, cancel
+}
+
+func (c *Client) attemptTimeout(attempt int) time.Duration {
+	if c.CancelAfterAttempt <= 0 || attempt < c.CancelAfterAttempt {
+		return c.PerAttemptTimeout
+	}
+	factor := c.CancelAfterAttemptFactor
+	if factor <= 0 {
+		factor = 0.5
+	}
+	return time.Duration(float64(c.PerAttemptTimeout) * factor)
+}
+
+type attemptKey struct{}
+
+// GetAttemptNumber returns the zero-based attempt number stored in contexts
+// of requests sent by Do, so that hooks, transports and handlers down the
+// line can tell retries apart. It returns 0 outside of Do.
+func GetAttemptNumber(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptKey{}).(int)
+	return attempt
+}
+
+// requestContext applies req.Timeout to the whole Do call unless the
+// request context already carries a deadline, and c.OverallTimeout in any
+// case.
+func (c *Client) requestContext(req *Request) (context.Context, context.CancelFunc) {
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if _, ok := ctx.Deadline(); !ok && req.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, req.Timeout)
+	}
+	if c.OverallTimeout > 0 {
+		var cancelOverall context.CancelFunc
+		ctx, cancelOverall = context.WithTimeout(ctx, c.OverallTimeout)
+		cancelRequest := cancel
+		cancel = func() {
+			cancelOverall()
+			cancelRequest()
+		}
+	}
+	return ctx, cancel
+}
+
+// cancelOnClose releases a context once the response body it guards is
+// closed, so the body can still be read after Do returns.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+func isTemporaryDNSError(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && (dnsErr.IsTemporary || dnsErr.IsTimeout)
+}
+
+func (c *Client) checkForRetry(attempt int, resp *http.Response, err error) (bool, error) {
+	retry, checkErr := c.policyCheck(attempt, resp, err)
+	if retry && c.targetUnhealthy() {
+		return false, ErrTargetUnhealthy
+	}
+	return retry, checkErr
+}
+
+func (c *Client) policyCheck(attempt int, resp *http.Response, err error) (bool, error) {
+	if c.RetryOnDNSFailure && isTemporaryDNSError(err) {
+		return true, nil
+	}
+	if c.digestAuth != nil && err == nil && c.digestAuth.challenge(resp) {
+		return true, nil
+	}
+	if c.ExtendedCheckForRetry != nil {
+		return c.ExtendedCheckForRetry(attempt, resp, err)
+	}
+	return c.CheckForRetry(resp, err)
+}
+
+const (
+	respReadLimit    = 1 << 20 // 1 Мб
+	drainBodyTimeout = time.Second
+)
+
+// drainBody discards the rest of body so that the connection can be reused.
+// It gives up when ctx is already done, and calls cancel after
+// drainBodyTimeout to abort a slow read, in which case the connection is
+// closed instead. cancel must release the context the request was sent with;
+// when nil, the one behind a body returned by Do is used.
+func (c *Client) drainBody(ctx context.Context, body io.ReadCloser, cancel context.CancelFunc) {
 	defer body.Close()
+	if ctx.Err() != nil {
+		return
+	}
+
+	if cancel == nil {
+		cancel = bodyCancel(body)
+	}
+	if cancel != nil {
+		timer := time.AfterFunc(drainBodyTimeout, cancel)
+		defer timer.Stop()
+	}
 	_, _ = io.Copy(ioutil.Discard, io.LimitReader(body, respReadLimit))
 }
 
+// bodyCancel returns a function cancelling every context released by the
+// cancelOnClose wrappers around body, the attempt context included, or nil
+// for other bodies.
+func bodyCancel(body io.ReadCloser) context.CancelFunc {
+	var cancels []context.CancelFunc
+	for {
+		guarded, ok := body.(*cancelOnClose)
+		if !ok {
+			break
+		}
+		cancels = append(cancels, guarded.cancel)
+		body = guarded.ReadCloser
+	}
+	if len(cancels) == 0 {
+		return nil
+	}
+	return func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+}
+
+const consumeReadLimit = 32 << 20 // 32 Мб
+
+var ErrBodyTooLarge = errors.New("response body too large")
+
+// Consume reads the whole response body and closes it. Bodies larger than
+// consumeReadLimit are rejected with ErrBodyTooLarge.
+func Consume(resp *http.Response) ([]byte, error) {
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, consumeReadLimit+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > consumeReadLimit {
+		return nil, ErrBodyTooLarge
+	}
+	return body, nil
+}
+
 func (c *Client) Get(url string) (*http.Response, error) {
 	req, err := NewRequest("GET", url, nil)
 	if err != nil {
@@ -123,11 +981,61 @@ func (c *Client) Get(url string) (*http.Response, error) {
 	return c.Do(req)
 }
 
+func (c *Client) Head(url string) (*http.Response, error) {
+	req, err := NewRequest("HEAD", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// GetHeaders returns only the response headers of a HEAD request to url,
+// e.g. to look at Content-Length or ETag before downloading a resource.
+// Non-2xx responses are reported as *HTTPError.
+func (c *Client) GetHeaders(url string) (http.Header, error) {
+	resp, err := c.Head(url)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.EnsureSuccess(resp); err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp.Header, nil
+}
+
 func (c *Client) Post(url, contentType string, body io.ReadSeeker) (*http.Response, error) {
-	req, err := NewRequest("Post", url, body)
+	req, err := NewRequest("POST", url, body)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", contentType)
 	return c.Do(req)
 }
+
+// Custom sends a request with an arbitrary method, e.g. PURGE or PROPFIND,
+// through the same retry logic as the other helpers.
+func (c *Client) Custom(method, url string, body io.ReadSeeker) (*http.Response, error) {
+	req, err := NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// Connect sends a CONNECT request for host ("host:port") to the server the
+// HTTPClient's transport dials, e.g. a proxy. The tunnel itself is not exposed:
+// http.Client gives no way to hijack the connection, so Connect is only useful
+// to tell a 200 Connection Established apart from a proxy error.
+func (c *Client) Connect(host string, headers http.Header) (*http.Response, error) {
+	req, err := NewRequest("CONNECT", "http://"+host, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	return c.Do(req)
+}
@@ -0,0 +1,360 @@
+package httpext
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a Client for srv that retries without sleeping.
+func newTestClient(srv *httptest.Server, retriesMax int) *Client {
+	c := NewClient(srv.Client())
+	c.RetriesMax = retriesMax
+	c.RetryWaitMin = 0
+	c.RetryWaitMax = 0
+	return c
+}
+
+// statusServer answers with the given status codes in order, repeating the
+// last one, and counts the requests it receives.
+func statusServer(t *testing.T, codes ...int) (*httptest.Server, *int32) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&hits, 1))
+		if n > len(codes) {
+			n = len(codes)
+		}
+		w.WriteHeader(codes[n-1])
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func TestRetriesMaxAttempts(t *testing.T) {
+	for _, retriesMax := range []int{0, 1, 2, 5} {
+		srv, hits := statusServer(t, http.StatusServiceUnavailable)
+		c := newTestClient(srv, retriesMax)
+
+		_, err := c.Get(srv.URL)
+		var maxErr *MaxRetriesExceededError
+		if !errors.As(err, &maxErr) {
+			t.Fatalf("RetriesMax %d: got error %v, want *MaxRetriesExceededError", retriesMax, err)
+		}
+		if got, want := int(atomic.LoadInt32(hits)), retriesMax+1; got != want {
+			t.Errorf("RetriesMax %d: server got %d requests, want %d", retriesMax, got, want)
+		}
+		if maxErr.Attempts != retriesMax+1 {
+			t.Errorf("RetriesMax %d: Attempts = %d, want %d", retriesMax, maxErr.Attempts, retriesMax+1)
+		}
+	}
+}
+
+func TestRetriesMaxZeroAndOne(t *testing.T) {
+	srv, hits := statusServer(t, http.StatusServiceUnavailable, http.StatusOK)
+	if _, err := newTestClient(srv, 0).Get(srv.URL); err == nil {
+		t.Fatal("RetriesMax 0: got no error, want the first 503 to be final")
+	}
+	if got := atomic.LoadInt32(hits); got != 1 {
+		t.Errorf("RetriesMax 0: server got %d requests, want 1", got)
+	}
+
+	srv, hits = statusServer(t, http.StatusServiceUnavailable, http.StatusOK)
+	resp, err := newTestClient(srv, 1).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("RetriesMax 1: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("RetriesMax 1: status %d, want 200", resp.StatusCode)
+	}
+	if got := atomic.LoadInt32(hits); got != 2 {
+		t.Errorf("RetriesMax 1: server got %d requests, want 2", got)
+	}
+}
+
+func TestGatewayErrorsAreRetried(t *testing.T) {
+	for _, code := range []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+		srv, hits := statusServer(t, code, http.StatusOK)
+		resp, err := newTestClient(srv, 2).Get(srv.URL)
+		if err != nil {
+			t.Fatalf("%d: %v", code, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%d: final status %d, want 200", code, resp.StatusCode)
+		}
+		if got := atomic.LoadInt32(hits); got != 2 {
+			t.Errorf("%d: server got %d requests, want 2", code, got)
+		}
+	}
+}
+
+func TestTooManyRequestsIsRetried(t *testing.T) {
+	srv, hits := statusServer(t,
+		http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusOK)
+	resp, err := newTestClient(srv, 5).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("final status %d, want 200", resp.StatusCode)
+	}
+	if got := atomic.LoadInt32(hits); got != 4 {
+		t.Errorf("server got %d requests, want 4", got)
+	}
+}
+
+func TestRequestTimeoutIsRetried(t *testing.T) {
+	srv, hits := statusServer(t, http.StatusRequestTimeout, http.StatusOK)
+	resp, err := newTestClient(srv, 2).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || atomic.LoadInt32(hits) != 2 {
+		t.Errorf("408: final status %d after %d requests, want 200 after 2", resp.StatusCode, atomic.LoadInt32(hits))
+	}
+
+	srv, hits = statusServer(t, http.StatusConflict, http.StatusOK)
+	resp, err = newTestClient(srv, 2).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict || atomic.LoadInt32(hits) != 1 {
+		t.Errorf("409: final status %d after %d requests, want 409 after 1", resp.StatusCode, atomic.LoadInt32(hits))
+	}
+}
+
+func TestPostSendsUppercaseMethod(t *testing.T) {
+	var method string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+	}))
+	defer srv.Close()
+
+	resp, err := newTestClient(srv, 0).Post(srv.URL, "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if method != "POST" {
+		t.Errorf("server got method %q, want %q", method, "POST")
+	}
+}
+
+func TestRetryOnDNSFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	var dials int32
+	dialer := &net.Dialer{}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if atomic.AddInt32(&dials, 1) == 1 {
+				return nil, &net.DNSError{Err: "server misbehaving", Name: "example.test", IsTemporary: true}
+			}
+			return dialer.DialContext(ctx, network, srv.Listener.Addr().String())
+		},
+	}
+	defer transport.CloseIdleConnections()
+
+	c := NewClient(&http.Client{Transport: transport})
+	c.RetriesMax, c.RetryWaitMin, c.RetryWaitMax = 2, 0, 0
+	c.CheckForRetry = NeverRetryPolicy
+	c.RetryOnDNSFailure = true
+
+	resp, err := c.Get("http://example.test/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := atomic.LoadInt32(&dials); got != 2 {
+		t.Errorf("dialed %d times, want 2", got)
+	}
+}
+
+func TestCustomMethodsAreRetried(t *testing.T) {
+	for _, method := range []string{"PURGE", "PROPFIND"} {
+		var hits int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != method {
+				t.Errorf("server got method %q, want %q", r.Method, method)
+			}
+			if atomic.AddInt32(&hits, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+
+		resp, err := newTestClient(srv, 2).Custom(method, srv.URL, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || atomic.LoadInt32(&hits) != 2 {
+			t.Errorf("%s: final status %d after %d requests, want 200 after 2", method, resp.StatusCode, atomic.LoadInt32(&hits))
+		}
+		srv.Close()
+	}
+}
+
+func TestOverallTimeoutDuringLastAttempt(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv, 0)
+	c.OverallTimeout = 50 * time.Millisecond
+	_, err := c.Get(srv.URL)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestDrainBodyTimeoutAbortsSlowBody(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv, 1)
+	c.ResponseBodyTransform = func(resp *http.Response) error {
+		resp.Body = ioutil.NopCloser(bufio.NewReader(resp.Body))
+		return nil
+	}
+
+	start := time.Now()
+	if _, err := c.Get(srv.URL); err == nil {
+		t.Fatal("got no error, want the 503 responses to exhaust the retries")
+	}
+	if elapsed := time.Since(start); elapsed > 2*drainBodyTimeout+time.Second {
+		t.Errorf("Do took %s, want the drains bounded by %s each", elapsed, drainBodyTimeout)
+	}
+}
+
+func TestValidateContentLength(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	c := newTestClient(srv, 0)
+	c.ValidateContentLength = true
+
+	req, err := NonRetryableRequest("PUT", srv.URL, bytes.NewBufferString("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("streamed body: %v", err)
+	}
+	resp.Body.Close()
+
+	req, err = NewRequest("PUT", srv.URL, strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.ContentLength = 4
+	if _, err := c.Do(req); !errors.Is(err, ErrContentLengthMismatch) {
+		t.Errorf("mismatched body: got error %v, want ErrContentLengthMismatch", err)
+	}
+}
+
+func TestRetryAllAsksEveryPolicyOnTransportErrors(t *testing.T) {
+	policy := RetryAll(DefaultRetryPolicy, ErrorTypeRetryPolicy((*net.DNSError)(nil)))
+
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	if retry, err := policy(nil, refused); retry || err != nil {
+		t.Errorf("connection refused: got (%v, %v), want (false, nil)", retry, err)
+	}
+
+	dnsErr := &net.DNSError{Err: "no such host", Name: "example.test"}
+	if retry, err := policy(nil, dnsErr); !retry || err != nil {
+		t.Errorf("DNS error: got (%v, %v), want (true, nil)", retry, err)
+	}
+}
+
+func TestRetryIdempotentOnly(t *testing.T) {
+	for method, want := range map[string]int32{"PUT": 3, "DELETE": 3, "POST": 1, "post": 1, "LOCK": 1, "MKCOL": 1} {
+		srv, hits := statusServer(t, http.StatusServiceUnavailable)
+		c := newTestClient(srv, 2)
+		c.RetryIdempotentOnly = true
+
+		if _, err := c.Custom(method, srv.URL, nil); err == nil {
+			t.Fatalf("%s: got no error, want the 503 to fail the request", method)
+		}
+		if got := atomic.LoadInt32(hits); got != want {
+			t.Errorf("%s: server got %d requests, want %d", method, got, want)
+		}
+	}
+}
+
+func TestDefaultQueryParamsKeepExistingQuery(t *testing.T) {
+	var rawQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawQuery = r.URL.RawQuery
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv, 0)
+	c.DefaultQueryParams = url.Values{"v": {"2"}, "z": {"9"}}
+	resp, err := c.Get(srv.URL + "/?z=1&a=b;c&sig=a%7Eb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want := "z=1&a=b;c&sig=a%7Eb&v=2"; rawQuery != want {
+		t.Errorf("server got query %q, want %q", rawQuery, want)
+	}
+}
+
+type staticProvider string
+
+func (p staticProvider) NewBody() (io.ReadSeeker, error) {
+	return strings.NewReader(string(p)), nil
+}
+
+func TestWithBodyHashRejectsUnreadableBodies(t *testing.T) {
+	streamed, err := NonRetryableRequest("POST", "http://example.test/", bytes.NewBufferString("important payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WithBodyHash(streamed); !errors.Is(err, ErrBodyNotReplayable) {
+		t.Errorf("streamed body: got error %v, want ErrBodyNotReplayable", err)
+	}
+
+	provided, err := NewRequestWithProvider("POST", "http://example.test/", staticProvider("important payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WithBodyHash(provided); !errors.Is(err, ErrBodyNotReplayable) {
+		t.Errorf("provided body: got error %v, want ErrBodyNotReplayable", err)
+	}
+
+	empty, err := NewRequest("GET", "http://example.test/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash, err := WithBodyHash(empty); err != nil || hash != sha256Hex(nil) {
+		t.Errorf("no body: got (%q, %v), want the hash of nothing", hash, err)
+	}
+}
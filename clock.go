@@ -0,0 +1,54 @@
+package httpext
+
+import (
+	"context"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type defaultClock struct{}
+
+func (defaultClock) Now() time.Time {
+	return time.Now()
+}
+
+func (defaultClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// SleepContext lets Do abandon the backoff as soon as ctx is done.
+func (defaultClock) SleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type contextSleeper interface {
+	SleepContext(ctx context.Context, d time.Duration) error
+}
+
+func (c *Client) clock() Clock {
+	if c.Clock == nil {
+		return defaultClock{}
+	}
+	return c.Clock
+}
+
+func (c *Client) sleep(ctx context.Context, d time.Duration) error {
+	clock := c.clock()
+	if sleeper, ok := clock.(contextSleeper); ok {
+		return sleeper.SleepContext(ctx, d)
+	}
+	clock.Sleep(d)
+	return ctx.Err()
+}
@@ -0,0 +1,97 @@
+package httpext
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+)
+
+// Config reports the active configuration in a JSON-serializable form, e.g.
+// for a health endpoint. Durations are rendered as strings and functions or
+// interfaces only as whether a custom one is installed. Default headers and
+// query parameters are listed by name only, since they may carry secrets.
+func (c *Client) Config() map[string]interface{} {
+	return map[string]interface{}{
+		"retries_max":                 c.RetriesMax,
+		"retry_wait_min":              c.RetryWaitMin.String(),
+		"retry_wait_max":              c.RetryWaitMax.String(),
+		"per_attempt_timeout":         c.PerAttemptTimeout.String(),
+		"cancel_after_attempt":        c.CancelAfterAttempt,
+		"cancel_after_attempt_factor": c.CancelAfterAttemptFactor,
+		"overall_timeout":             c.OverallTimeout.String(),
+		"connect_timeout":             c.ConnectTimeout.String(),
+		"tls_handshake_timeout":       c.TLSHandshakeTimeout.String(),
+		"response_header_timeout":     c.ResponseHeaderTimeout.String(),
+		"disable_compression":         c.DisableCompression,
+		"has_custom_backoff":          !sameFunc(c.Backoff, DefaultBackoff),
+		"has_custom_retry_policy":     c.ExtendedCheckForRetry != nil || !sameFunc(c.CheckForRetry, DefaultRetryPolicy),
+		"retry_on_dns_failure":        c.RetryOnDNSFailure,
+		"health_check_url":            c.HealthCheckURL,
+		"health_check_interval":       c.HealthCheckInterval.String(),
+		"has_cache":                   c.Cache != nil,
+		"cache_ttl":                   c.CacheTTL.String(),
+		"cache_key_headers":           c.CacheKeyHeaders,
+		"status_error_map":            statusCodes(c.StatusErrorMap),
+		"validate_content_length":     c.ValidateContentLength,
+		"max_body_retry_size_bytes":   c.MaxBodyRetrySizeBytes,
+		"preflight_head":              c.PreflightHEAD,
+		"has_interceptor":             c.Interceptor != nil,
+		"middleware":                  len(c.Middleware),
+		"request_hooks":               len(c.RequestHooks),
+		"max_concurrency":             c.MaxConcurrency,
+		"deduplicate_get":             c.DeduplicateGET,
+		"user_agent":                  c.UserAgent,
+		"default_headers":             headerNames(c.DefaultHeaders),
+		"default_query_params":        queryParamNames(c.DefaultQueryParams),
+		"sanitize_query_params":       c.SanitizeQueryParams,
+		"normalize_url":               c.NormalizeURL,
+		"host_override":               c.HostOverride,
+		"correlation_id_header":       c.CorrelationIDHeader,
+		"retry_idempotent_only":       c.RetryIdempotentOnly,
+		"has_response_body_transform": c.ResponseBodyTransform != nil,
+		"has_signature_verifier":      c.ResponseSignatureVerifier != nil,
+		"capture_trailers":            c.CaptureTrailers,
+		"collect_all_responses":       c.CollectAllResponses,
+		"has_trace_context":           c.TraceContext != nil,
+		"debug":                       c.DebugLogger != nil,
+		"debug_body_limit":            c.DebugBodyLimit,
+		"has_custom_clock":            c.Clock != nil,
+	}
+}
+
+// sameFunc reports whether a and b refer to the same top-level function.
+func sameFunc(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.IsNil() || vb.IsNil() {
+		return va.IsNil() == vb.IsNil()
+	}
+	return va.Pointer() == vb.Pointer()
+}
+
+func statusCodes(m map[int]error) []int {
+	codes := make([]int, 0, len(m))
+	for code := range m {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	return codes
+}
+
+func headerNames(h http.Header) []string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func queryParamNames(values url.Values) []string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
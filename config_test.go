@@ -0,0 +1,25 @@
+package httpext
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestConfigIsJSONAndHidesHeaderValues(t *testing.T) {
+	c := NewClient(nil)
+	c.DefaultHeaders = http.Header{"Authorization": {"Bearer secret"}}
+	c.StatusErrorMap = map[int]error{http.StatusNotFound: nil}
+
+	data, err := json.Marshal(c.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("Config leaks a default header value: %s", data)
+	}
+	if !strings.Contains(string(data), `"default_headers":["Authorization"]`) {
+		t.Errorf("Config lacks the default header names: %s", data)
+	}
+}
@@ -0,0 +1,98 @@
+package httpext
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+}
+
+type curlConfig struct {
+	includeSensitive bool
+}
+
+type CurlOption func(cfg *curlConfig)
+
+// CurlIncludeSensitiveHeaders disables redaction of Authorization,
+// Proxy-Authorization and Cookie headers.
+func CurlIncludeSensitiveHeaders() CurlOption {
+	return func(cfg *curlConfig) {
+		cfg.includeSensitive = true
+	}
+}
+
+// BuildCurlCommand renders req as a shell-escaped curl invocation. Sensitive
+// headers are replaced with [REDACTED] unless CurlIncludeSensitiveHeaders is
+// given. The body is read from the start and rewound afterwards.
+func BuildCurlCommand(req *Request, opts ...CurlOption) string {
+	var cfg curlConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	parts := []string{"curl", "-X", shellQuote(req.Method), shellQuote(req.URL.String())}
+
+	if req.Host != "" && req.Host != req.URL.Host {
+		parts = append(parts, "-H", shellQuote("Host: "+req.Host))
+	}
+
+	keys := make([]string, 0, len(req.Header))
+	for key := range req.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range req.Header[key] {
+			if !cfg.includeSensitive && sensitiveHeaders[http.CanonicalHeaderKey(key)] {
+				value = "[REDACTED]"
+			}
+			parts = append(parts, "-H", shellQuote(key+": "+value))
+		}
+	}
+
+	if body, err := peekBody(req, -1); err == nil && len(body) > 0 {
+		parts = append(parts, "--data-binary", shellQuote(string(body)))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+var ErrBodyNotReplayable = errors.New("request body cannot be read ahead of sending")
+
+// peekBody reads up to limit bytes (everything when limit is negative) of the
+// request body and rewinds it, leaving the request ready to be sent. Streamed
+// bodies and bodies from a BodyProvider not yet built fail with
+// ErrBodyNotReplayable rather than reading as empty.
+func peekBody(req *Request, limit int64) ([]byte, error) {
+	if req.body == nil {
+		if req.provider != nil || req.Request.Body != nil && req.Request.Body != http.NoBody {
+			return nil, ErrBodyNotReplayable
+		}
+		return nil, nil
+	}
+	if _, err := req.body.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var r io.Reader = req.body
+	if limit >= 0 {
+		r = io.LimitReader(r, limit)
+	}
+	body, err := ioutil.ReadAll(r)
+	if _, seekErr := req.body.Seek(0, io.SeekStart); err == nil {
+		err = seekErr
+	}
+	return body, err
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,93 @@
+package httpext
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Logger is satisfied by *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+const (
+	defaultDebugBodyLimit = 512
+	formatBodyLimit       = 4096
+)
+
+func (c *Client) debugBodyLimit() int {
+	if c.DebugBodyLimit > 0 {
+		return c.DebugBodyLimit
+	}
+	return defaultDebugBodyLimit
+}
+
+func (c *Client) debugRequest(req *Request, attempt int) {
+	body, err := peekBody(req, int64(c.debugBodyLimit()))
+	if err != nil {
+		c.DebugLogger.Printf("%s %s attempt %d: reading body: %v", req.Method, c.redactURL(req.URL), attempt, err)
+		return
+	}
+	c.DebugLogger.Printf("%s %s attempt %d: body %q", req.Method, c.redactURL(req.URL), attempt, body)
+}
+
+// debugResponse logs the beginning of the response body and puts the bytes
+// it read back in front of the remaining body.
+func (c *Client) debugResponse(resp *http.Response) {
+	snippet, err := ioutil.ReadAll(io.LimitReader(resp.Body, int64(c.debugBodyLimit())))
+	resp.Body = &prefixedBody{
+		Reader: io.MultiReader(bytes.NewReader(snippet), resp.Body),
+		Closer: resp.Body,
+	}
+	if err != nil {
+		c.DebugLogger.Printf("response %s: reading body: %v", resp.Status, err)
+		return
+	}
+	c.DebugLogger.Printf("response %s: body %q", resp.Status, snippet)
+}
+
+type prefixedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// FormatRequest renders req roughly as it goes over the wire: the request
+// line, the Host header, the other headers sorted by name and, when
+// includeBody is set, up to formatBodyLimit bytes of the body. The body is
+// rewound afterwards.
+func FormatRequest(req *Request, includeBody bool) string {
+	var b strings.Builder
+	b.WriteString(req.Method + " " + req.URL.String() + " HTTP/1.1\r\n")
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	b.WriteString("Host: " + host + "\r\n")
+
+	keys := make([]string, 0, len(req.Header))
+	for key := range req.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range req.Header[key] {
+			b.WriteString(key + ": " + value + "\r\n")
+		}
+	}
+	b.WriteString("\r\n")
+
+	if includeBody {
+		body, err := peekBody(req, formatBodyLimit)
+		if err != nil {
+			b.WriteString("[reading body: " + err.Error() + "]")
+		} else {
+			b.Write(body)
+		}
+	}
+	return b.String()
+}
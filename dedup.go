@@ -0,0 +1,35 @@
+package httpext
+
+import (
+	"net/http"
+)
+
+// doGET sends a GET request, shared with identical concurrent ones when
+// c.DeduplicateGET is set. Range requests are never shared, since the
+// cache key does not tell the ranges apart.
+func (c *Client) doGET(req *Request) (*http.Response, error) {
+	if c.DeduplicateGET && req.Header.Get("Range") == "" {
+		return c.doShared(req)
+	}
+	return c.do(req)
+}
+
+// doShared coalesces concurrent GET requests with the same cache key into a
+// single call. The body is buffered so that every waiter gets its own copy.
+// The retries, timeouts and context of the first caller apply to all of them.
+func (c *Client) doShared(req *Request) (*http.Response, error) {
+	v, err, _ := c.getGroup.Do(c.cacheKey(req), func() (interface{}, error) {
+		resp, err := c.do(req)
+		if err != nil {
+			return nil, err
+		}
+		if err := bufferResponse(resp); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyBufferedResponse(v.(*http.Response)), nil
+}
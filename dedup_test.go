@@ -0,0 +1,47 @@
+package httpext
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDeduplicateGETKeepsCredentialsApart(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv, 0)
+	c.DeduplicateGET = true
+	c.Cache = NewMemoryCache()
+
+	var wg sync.WaitGroup
+	for _, token := range []string{"Bearer alice", "Bearer bob"} {
+		wg.Add(1)
+		go func(token string) {
+			defer wg.Done()
+			req, err := NewRequest("GET", srv.URL, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			req.Header.Set("Authorization", token)
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(body) != token {
+				t.Errorf("%s got the response for %q", token, body)
+			}
+		}(token)
+	}
+	wg.Wait()
+}
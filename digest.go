@@ -0,0 +1,207 @@
+package httpext
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+type DigestAuthConfig struct {
+	Username string
+	Password string
+}
+
+// WithDigestAuth answers HTTP Digest challenges (RFC 7616, MD5 or SHA-256
+// with qop=auth). The first request goes out without credentials; a 401
+// carrying a Digest challenge is retried with an Authorization header, so
+// RetriesMax must be at least 1. Challenges are kept per origin: later
+// requests to the same scheme and host reuse theirs until the server marks
+// it stale, and other hosts never receive the credentials.
+// Other responses are left to the retry policy.
+func WithDigestAuth(cfg DigestAuthConfig) Option {
+	return func(c *Client) {
+		c.digestAuth = &digestAuth{cfg: cfg, challenges: make(map[string]*digestChallenge)}
+		c.RequestHooks = append(c.RequestHooks, c.digestAuth.authorize)
+	}
+}
+
+type digestAuth struct {
+	cfg DigestAuthConfig
+
+	mu         sync.Mutex
+	challenges map[string]*digestChallenge
+}
+
+type digestChallenge struct {
+	params map[string]string
+	nc     int
+}
+
+func digestOrigin(u *url.URL) string {
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// challenge records the Digest challenge of a 401 response for the origin
+// of its request and reports whether the request should be sent again with
+// credentials.
+func (d *digestAuth) challenge(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusUnauthorized || resp.Request == nil {
+		return false
+	}
+	params, ok := parseDigestChallenge(resp.Header.Values("Www-Authenticate"))
+	if !ok {
+		return false
+	}
+	// Credentials that were rejected are not retried, unless the server
+	// only refused an outdated nonce.
+	sent := resp.Request.Header.Get("Authorization") != ""
+	if sent && !strings.EqualFold(params["stale"], "true") {
+		return false
+	}
+
+	d.mu.Lock()
+	d.challenges[digestOrigin(resp.Request.URL)] = &digestChallenge{params: params}
+	d.mu.Unlock()
+	return true
+}
+
+func (d *digestAuth) authorize(req *Request) error {
+	d.mu.Lock()
+	ch := d.challenges[digestOrigin(req.URL)]
+	var params map[string]string
+	var nc int
+	if ch != nil {
+		ch.nc++
+		params, nc = ch.params, ch.nc
+	}
+	d.mu.Unlock()
+	if params == nil {
+		return nil
+	}
+
+	var h func() hash.Hash
+	switch strings.ToUpper(params["algorithm"]) {
+	case "", "MD5":
+		h = md5.New
+	case "SHA-256":
+		h = sha256.New
+	default:
+		return fmt.Errorf("unsupported digest algorithm %q", params["algorithm"])
+	}
+	digest := func(s string) string {
+		sum := h()
+		sum.Write([]byte(s))
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	uri := req.URL.RequestURI()
+	ha1 := digest(d.cfg.Username + ":" + params["realm"] + ":" + d.cfg.Password)
+	ha2 := digest(req.Method + ":" + uri)
+
+	fields := []string{
+		fmt.Sprintf("username=%q", d.cfg.Username),
+		fmt.Sprintf("realm=%q", params["realm"]),
+		fmt.Sprintf("nonce=%q", params["nonce"]),
+		fmt.Sprintf("uri=%q", uri),
+	}
+	if qopAuth(params["qop"]) {
+		cnonce, err := newCnonce()
+		if err != nil {
+			return err
+		}
+		ncValue := fmt.Sprintf("%08x", nc)
+		response := digest(strings.Join([]string{ha1, params["nonce"], ncValue, cnonce, "auth", ha2}, ":"))
+		fields = append(fields,
+			"qop=auth",
+			"nc="+ncValue,
+			fmt.Sprintf("cnonce=%q", cnonce),
+			fmt.Sprintf("response=%q", response),
+		)
+	} else {
+		fields = append(fields, fmt.Sprintf("response=%q", digest(ha1+":"+params["nonce"]+":"+ha2)))
+	}
+	if algorithm, ok := params["algorithm"]; ok {
+		fields = append(fields, "algorithm="+algorithm)
+	}
+	if opaque, ok := params["opaque"]; ok {
+		fields = append(fields, fmt.Sprintf("opaque=%q", opaque))
+	}
+
+	req.Header.Set("Authorization", "Digest "+strings.Join(fields, ", "))
+	return nil
+}
+
+func qopAuth(qop string) bool {
+	for _, v := range strings.Split(qop, ",") {
+		if strings.TrimSpace(v) == "auth" {
+			return true
+		}
+	}
+	return false
+}
+
+func newCnonce() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// parseDigestChallenge returns the parameters of the first Digest
+// challenge among the given WWW-Authenticate header values.
+func parseDigestChallenge(values []string) (map[string]string, bool) {
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if len(v) < 7 || !strings.EqualFold(v[:7], "Digest ") {
+			continue
+		}
+		params := make(map[string]string)
+		rest := v[7:]
+		for rest != "" {
+			rest = strings.TrimLeft(rest, " ,")
+			eq := strings.IndexByte(rest, '=')
+			if eq < 0 {
+				break
+			}
+			key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+			rest = strings.TrimLeft(rest[eq+1:], " ")
+
+			var value string
+			if strings.HasPrefix(rest, `"`) {
+				var b strings.Builder
+				i := 1
+				for ; i < len(rest) && rest[i] != '"'; i++ {
+					if rest[i] == '\\' && i+1 < len(rest) {
+						i++
+					}
+					b.WriteByte(rest[i])
+				}
+				if i < len(rest) {
+					i++
+				}
+				value = b.String()
+				rest = rest[i:]
+			} else {
+				end := strings.IndexByte(rest, ',')
+				if end < 0 {
+					end = len(rest)
+				}
+				value = strings.TrimSpace(rest[:end])
+				rest = rest[end:]
+			}
+			params[key] = value
+		}
+		if params["nonce"] != "" {
+			return params, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,82 @@
+package httpext
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDigestAuthWithLaterRetryPolicy(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		params, ok := parseDigestChallenge([]string{r.Header.Get("Authorization")})
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Digest realm="test", qop="auth", nonce="abc", opaque="xyz"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		ha1 := md5Hex("user:test:pass")
+		ha2 := md5Hex(r.Method + ":" + params["uri"])
+		want := md5Hex(strings.Join([]string{ha1, "abc", params["nc"], params["cnonce"], "auth", ha2}, ":"))
+		if params["response"] != want || params["opaque"] != "xyz" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.Client(), WithDigestAuth(DigestAuthConfig{Username: "user", Password: "pass"}))
+	c.RetryWaitMin, c.RetryWaitMax = 0, 0
+	c.CheckForRetry = NeverRetryPolicy
+
+	resp, err := c.Get(srv.URL + "/dir/index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status %d, want 200", resp.StatusCode)
+	}
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("server got %d requests, want 2", got)
+	}
+}
+
+func TestDigestAuthStaysWithChallengingHost(t *testing.T) {
+	challenger := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.Header().Set("WWW-Authenticate", `Digest realm="test", qop="auth", nonce="abc"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer challenger.Close()
+
+	var leaked string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked = r.Header.Get("Authorization")
+	}))
+	defer other.Close()
+
+	c := NewClient(http.DefaultClient, WithDigestAuth(DigestAuthConfig{Username: "user", Password: "pass"}))
+	c.RetryWaitMin, c.RetryWaitMax = 0, 0
+
+	for _, u := range []string{challenger.URL, other.URL} {
+		resp, err := c.Get(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if leaked != "" {
+		t.Errorf("unrelated host received Authorization %q", leaked)
+	}
+}
@@ -0,0 +1,183 @@
+package httpext
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ResumeDownload GETs url into dest. rangeHeader optionally restricts the
+// download to "bytes=N-" or "bytes=N-M" and is empty for the whole resource;
+// bytes are written to dest at their offset within the resource. When the
+// body breaks off mid-transfer, the download is resumed with a Range request
+// starting at the first missing byte, up to RetriesMax times. Resumed
+// requests carry If-Range with the ETag or Last-Modified of the first
+// response, so that a resource changed in between is downloaded again from
+// the start rather than spliced.
+func (c *Client) ResumeDownload(ctx context.Context, rawURL string, dest io.WriterAt, rangeHeader string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	start, end, err := parseByteRange(rangeHeader)
+	if err != nil {
+		return err
+	}
+
+	offset := start
+	var validator string
+	for i := 0; ; i++ {
+		err := c.downloadFrom(ctx, u, dest, start, &offset, end, &validator)
+		if _, ok := err.(*resumableError); !ok {
+			return err
+		}
+
+		if i == c.RetriesMax {
+			return fmt.Errorf("GET %s giving up after %d interrupted downloads: %w", c.redactURL(u), i+1, err)
+		}
+		wait := c.Backoff(c.RetryWaitMin, c.RetryWaitMax, i, nil)
+		if err := c.sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// resumableError wraps an error from reading the response body, after which
+// the download can continue from offset.
+type resumableError struct {
+	err error
+}
+
+func (e *resumableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *resumableError) Unwrap() error {
+	return e.err
+}
+
+// downloadFrom requests the bytes from *offset on and writes them to dest,
+// advancing *offset. It returns nil once nothing is left to download.
+// *validator holds the If-Range value for resumed requests and is set from
+// the first response.
+func (c *Client) downloadFrom(ctx context.Context, u *url.URL, dest io.WriterAt, start int64, offset *int64, end int64, validator *string) error {
+	req, err := NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Request = req.Request.WithContext(ctx)
+	if *offset > 0 || end >= 0 {
+		rangeValue := "bytes=" + strconv.FormatInt(*offset, 10) + "-"
+		if end >= 0 {
+			rangeValue += strconv.FormatInt(end, 10)
+		}
+		req.Header.Set("Range", rangeValue)
+	}
+	resuming := *offset > start && *validator != ""
+	if resuming {
+		req.Header.Set("If-Range", *validator)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+		first, ok := contentRangeStart(resp.Header.Get("Content-Range"))
+		if !ok || first != *offset {
+			return fmt.Errorf("GET %s: Content-Range %q does not start at byte %d", c.redactURL(u), resp.Header.Get("Content-Range"), *offset)
+		}
+	case resp.StatusCode == http.StatusOK && start == 0:
+		// The server ignored the Range header or, through If-Range, reports
+		// that the resource changed: it sent the whole resource.
+		*offset = 0
+		*validator = ""
+	case resp.StatusCode == http.StatusOK && resuming:
+		return fmt.Errorf("GET %s: resource changed during the download", c.redactURL(u))
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && *offset > start:
+		// Everything was written before the stream broke off.
+		return nil
+	case resp.StatusCode == http.StatusOK:
+		return fmt.Errorf("GET %s: server does not support range requests", c.redactURL(u))
+	default:
+		return c.EnsureSuccess(resp)
+	}
+
+	if *validator == "" {
+		*validator = rangeValidator(resp)
+	}
+
+	buf := make([]byte, 32<<10)
+	for {
+		n, readErr := resp.Body.Read(buf)
+		if n > 0 {
+			if _, err := dest.WriteAt(buf[:n], *offset); err != nil {
+				return err
+			}
+			*offset += int64(n)
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return &resumableError{err: readErr}
+		}
+	}
+}
+
+// rangeValidator returns the strong ETag of resp, or else its
+// Last-Modified date, for use in If-Range.
+func rangeValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("Etag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+// contentRangeStart returns the first byte position of a
+// "bytes first-last/length" Content-Range header.
+func contentRangeStart(header string) (int64, bool) {
+	spec := strings.TrimPrefix(header, "bytes ")
+	dash := strings.IndexByte(spec, '-')
+	if spec == header || dash < 0 {
+		return 0, false
+	}
+	first, err := strconv.ParseInt(spec[:dash], 10, 64)
+	return first, err == nil
+}
+
+// parseByteRange parses "bytes=N-" and "bytes=N-M"; end is -1 when open.
+func parseByteRange(header string) (start, end int64, err error) {
+	if header == "" {
+		return 0, -1, nil
+	}
+
+	spec := strings.TrimPrefix(header, "bytes=")
+	parts := strings.Split(spec, "-")
+	if spec == header || len(parts) != 2 {
+		return 0, 0, fmt.Errorf("unsupported range %q", header)
+	}
+
+	start, err = strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, fmt.Errorf("unsupported range %q", header)
+	}
+	if parts[1] == "" {
+		return start, -1, nil
+	}
+	end, err = strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || end < start {
+		return 0, 0, fmt.Errorf("unsupported range %q", header)
+	}
+	return start, end, nil
+}
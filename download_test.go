@@ -0,0 +1,65 @@
+package httpext
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memoryWriterAt is an io.WriterAt over a fixed-size buffer.
+type memoryWriterAt []byte
+
+func (m memoryWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	return copy(m[off:], p), nil
+}
+
+// breakOffServer sends the first five bytes of first with ETag "v1" and
+// drops the connection, then serves next with ETag "v2".
+func breakOffServer(t *testing.T, first, next string) *httptest.Server {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.Header().Set("Etag", `"v1"`)
+			w.Header().Set("Content-Length", "10")
+			w.Write([]byte(first[:5]))
+			w.(http.Flusher).Flush()
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		w.Header().Set("Etag", `"v2"`)
+		http.ServeContent(w, r, "data", time.Time{}, strings.NewReader(next))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestResumeDownloadRestartsChangedResource(t *testing.T) {
+	srv := breakOffServer(t, "0123456789", "abcdefghij")
+	dest := make(memoryWriterAt, 10)
+
+	if err := newTestClient(srv, 2).ResumeDownload(context.Background(), srv.URL, dest, ""); err != nil {
+		t.Fatal(err)
+	}
+	if string(dest) != "abcdefghij" {
+		t.Errorf("dest = %q, want %q", dest, "abcdefghij")
+	}
+}
+
+func TestResumeDownloadChecksContentRange(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", "bytes 0-9/10")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("0123456789"))
+	}))
+	defer srv.Close()
+
+	dest := make(memoryWriterAt, 10)
+	if err := newTestClient(srv, 0).ResumeDownload(context.Background(), srv.URL, dest, "bytes=5-"); err == nil {
+		t.Error("got no error for a Content-Range that does not match the requested offset")
+	}
+}
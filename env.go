@@ -0,0 +1,102 @@
+package httpext
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	envRetriesMax   = "HTTPEXT_RETRY_MAX"
+	envRetryWaitMin = "HTTPEXT_RETRY_WAIT_MIN"
+	envRetryWaitMax = "HTTPEXT_RETRY_WAIT_MAX"
+)
+
+// NewClientFromEnv is like NewClientFromEnvE but panics on invalid
+// configuration, the same way NewClient does.
+func NewClientFromEnv(opts ...Option) *Client {
+	c, err := NewClientFromEnvE(opts...)
+	if err != nil {
+		panic("httpext: " + err.Error())
+	}
+	return c
+}
+
+// NewClientFromEnvE builds a Client whose retry settings are read from
+// HTTPEXT_RETRY_MAX (an integer), HTTPEXT_RETRY_WAIT_MIN and
+// HTTPEXT_RETRY_WAIT_MAX (time.ParseDuration format). Unset variables keep
+// the package defaults; opts are applied on top of the environment.
+func NewClientFromEnvE(opts ...Option) (*Client, error) {
+	envOpt, err := optionFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	c := newClient(&http.Client{}, append([]Option{envOpt}, opts...))
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func optionFromEnv() (Option, error) {
+	retriesMax := defaultRetriesMax
+	if value, ok := os.LookupEnv(envRetriesMax); ok {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", envRetriesMax, err)
+		}
+		retriesMax = n
+	}
+
+	waitMin, err := durationFromEnv(envRetryWaitMin, defaultRetryWaitMin)
+	if err != nil {
+		return nil, err
+	}
+	waitMax, err := durationFromEnv(envRetryWaitMax, defaultRetryWaitMax)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *Client) {
+		c.RetriesMax = retriesMax
+		c.RetryWaitMin = waitMin
+		c.RetryWaitMax = waitMax
+	}, nil
+}
+
+func durationFromEnv(key string, fallback time.Duration) (time.Duration, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return d, nil
+}
+
+// SetBasicAuthFromEnv reads the credentials from the given environment
+// variables and installs a RequestHook that sends them with every request.
+// It fails when either variable is empty, so that a misconfigured deployment
+// is caught at startup.
+func (c *Client) SetBasicAuthFromEnv(userEnvKey, passEnvKey string) error {
+	user := os.Getenv(userEnvKey)
+	if user == "" {
+		return fmt.Errorf("%s is not set", userEnvKey)
+	}
+	pass := os.Getenv(passEnvKey)
+	if pass == "" {
+		return fmt.Errorf("%s is not set", passEnvKey)
+	}
+
+	c.RequestHooks = append(c.RequestHooks, func(req *Request) error {
+		req.SetBasicAuth(user, pass)
+		return nil
+	})
+	return nil
+}
@@ -0,0 +1,98 @@
+package httpext
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Method     string
+	URL        string
+	Body       []byte
+
+	// Err is the error mapped to StatusCode by Client.StatusErrorMap, so
+	// that callers can match it with errors.Is.
+	Err error
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s %s unexpected status: %s", e.Method, e.URL, e.Status)
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// MaxRetriesExceededError is returned by Do once every attempt has been
+// retried. LastResponse is the most recent response received, if any; its
+// body has already been drained and closed, but the status code and headers
+// are still available.
+type MaxRetriesExceededError struct {
+	Method string
+	URL    string
+
+	// Attempts counts the first attempt as well as the retries, so it is
+	// RetriesMax+1.
+	Attempts     int
+	LastResponse *http.Response
+}
+
+func (e *MaxRetriesExceededError) Error() string {
+	return fmt.Sprintf("%s %s giving up after %d attempts", e.Method, e.URL, e.Attempts)
+}
+
+// SeekError reports that the request body could not be rewound before the
+// given zero-based attempt, which usually means it is not really seekable.
+type SeekError struct {
+	Attempt int
+	URL     string
+	Cause   error
+}
+
+func (e *SeekError) Error() string {
+	return fmt.Sprintf("%s: rewinding body before attempt %d: %v", e.URL, e.Attempt, e.Cause)
+}
+
+func (e *SeekError) Unwrap() error {
+	return e.Cause
+}
+
+// EnsureSuccess returns nil for 2xx responses. Otherwise it reads (up to
+// respReadLimit) and closes the body and returns it wrapped in *HTTPError.
+func EnsureSuccess(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, respReadLimit))
+
+	httpErr := &HTTPError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Body:       body,
+	}
+	if resp.Request != nil {
+		httpErr.Method = resp.Request.Method
+		httpErr.URL = resp.Request.URL.String()
+	}
+	return httpErr
+}
+
+// EnsureSuccess is like the package-level EnsureSuccess, but also attaches
+// the error that c.StatusErrorMap maps the status code to and applies
+// c.SanitizeQueryParams to the URL.
+func (c *Client) EnsureSuccess(resp *http.Response) error {
+	err := EnsureSuccess(resp)
+	if httpErr, ok := err.(*HTTPError); ok {
+		httpErr.Err = c.StatusErrorMap[httpErr.StatusCode]
+		if resp.Request != nil {
+			httpErr.URL = c.redactURL(resp.Request.URL)
+		}
+	}
+	return err
+}
@@ -1,3 +1,5 @@
 module github.com/axelzv9/httpext
 
 go 1.15
+
+require golang.org/x/sync v0.1.0
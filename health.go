@@ -0,0 +1,96 @@
+package httpext
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const defaultHealthCheckInterval = 10 * time.Second
+
+var ErrTargetUnhealthy = errors.New("target reported unhealthy by health check")
+
+func (c *Client) targetUnhealthy() bool {
+	return atomic.LoadInt32(&c.unhealthy) == 1
+}
+
+func (c *Client) startHealthCheck() {
+	c.healthOnce.Do(func() {
+		c.healthStop = make(chan struct{})
+		c.healthDone = make(chan struct{})
+		go c.pollHealth(c.healthStop, c.healthDone)
+	})
+}
+
+// StopHealthCheck stops polling HealthCheckURL and waits for the poller to
+// exit. Retries are no longer skipped afterwards, and Do does not start the
+// poller again. Call it, or GracefulShutdown, once done with a Client that
+// has a HealthCheckURL, since the poller otherwise keeps it alive forever.
+func (c *Client) StopHealthCheck() {
+	c.healthOnce.Do(func() {})
+	c.healthStopOnce.Do(func() {
+		if c.healthStop != nil {
+			close(c.healthStop)
+			<-c.healthDone
+		}
+		atomic.StoreInt32(&c.unhealthy, 0)
+	})
+}
+
+func (c *Client) pollHealth(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	interval := c.HealthCheckInterval
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		c.checkHealth(ctx, interval)
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// checkHealth queries HealthCheckURL once, without retries. A check
+// cancelled through ctx leaves the state untouched.
+func (c *Client) checkHealth(ctx context.Context, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	healthy := false
+	req, err := http.NewRequestWithContext(ctx, "GET", c.HealthCheckURL, nil)
+	if err == nil {
+		var resp *http.Response
+		if resp, err = c.httpClient().Do(req); err == nil {
+			healthy = resp.StatusCode >= 200 && resp.StatusCode < 300
+			c.drainBody(ctx, resp.Body, cancel)
+		}
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	var state int32
+	if !healthy {
+		state = 1
+	}
+	atomic.StoreInt32(&c.unhealthy, state)
+}
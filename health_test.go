@@ -0,0 +1,76 @@
+package httpext
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStopHealthCheck(t *testing.T) {
+	var checks int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			atomic.AddInt32(&checks, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv, 0)
+	c.HealthCheckURL = srv.URL + "/health"
+	c.HealthCheckInterval = 10 * time.Millisecond
+
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	c.StopHealthCheck()
+	if c.targetUnhealthy() {
+		t.Error("target still reported unhealthy after StopHealthCheck")
+	}
+	// A check cancelled by StopHealthCheck may still reach the server.
+	time.Sleep(20 * time.Millisecond)
+	stopped := atomic.LoadInt32(&checks)
+	if stopped == 0 {
+		t.Fatal("health endpoint was never polled")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := atomic.LoadInt32(&checks); got != stopped {
+		t.Errorf("health endpoint polled %d more times after StopHealthCheck", got-stopped)
+	}
+}
+
+func TestUnhealthyTargetReleasesResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv, 2)
+	c.HealthCheckURL = srv.URL + "/health"
+	c.HealthCheckInterval = 10 * time.Millisecond
+	defer c.StopHealthCheck()
+
+	c.startHealthCheck()
+	for deadline := time.Now().Add(time.Second); !c.targetUnhealthy(); {
+		if time.Now().After(deadline) {
+			t.Fatal("health check never reported the target unhealthy")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	resp, err := c.Get(srv.URL)
+	if !errors.Is(err, ErrTargetUnhealthy) {
+		t.Errorf("got error %v, want ErrTargetUnhealthy", err)
+	}
+	if resp != nil {
+		resp.Body.Close()
+		t.Error("got a response alongside ErrTargetUnhealthy")
+	}
+}
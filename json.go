@@ -0,0 +1,124 @@
+package httpext
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+const jsonContentType = "application/json"
+
+func (c *Client) GetJSON(url string, out interface{}) error {
+	req, err := NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", jsonContentType)
+	return c.doJSON(req, out)
+}
+
+func (c *Client) PostJSON(url string, in interface{}, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", jsonContentType)
+	req.Header.Set("Accept", jsonContentType)
+	return c.doJSON(req, out)
+}
+
+func (c *Client) doJSON(req *Request, out interface{}) error {
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	if err := c.EnsureSuccess(resp); err != nil {
+		return err
+	}
+	defer c.drainBody(req.Context(), resp.Body, nil)
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// JSONStream iterates over the values of a newline-delimited JSON body or
+// of a top-level JSON array, in the spirit of bufio.Scanner:
+//
+//	stream, err := httpext.DecodeJSONStream(resp)
+//	for stream.Next(&item) {
+//		...
+//	}
+//	err = stream.Err()
+type JSONStream struct {
+	dec     *json.Decoder
+	inArray bool
+	err     error
+}
+
+// DecodeJSONStream starts decoding resp.Body. The caller stays responsible
+// for closing the body once done with the stream.
+func DecodeJSONStream(resp *http.Response) (*JSONStream, error) {
+	r := bufio.NewReader(resp.Body)
+	first, err := peekNonSpace(r)
+	if err == io.EOF {
+		return &JSONStream{dec: json.NewDecoder(r)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &JSONStream{dec: json.NewDecoder(r)}
+	if first == '[' {
+		if _, err := stream.dec.Token(); err != nil {
+			return nil, err
+		}
+		stream.inArray = true
+	}
+	return stream, nil
+}
+
+// Next decodes the next value into v. It returns false at the end of the
+// stream or on error; Err tells the two apart.
+func (s *JSONStream) Next(v interface{}) bool {
+	if s.err != nil {
+		return false
+	}
+	if s.inArray && !s.dec.More() {
+		return false
+	}
+
+	if err := s.dec.Decode(v); err != nil {
+		if err != io.EOF || s.inArray {
+			s.err = err
+		}
+		return false
+	}
+	return true
+}
+
+func (s *JSONStream) Err() error {
+	return s.err
+}
+
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, r.UnreadByte()
+	}
+}
@@ -0,0 +1,28 @@
+package httpext
+
+import (
+	"sync/atomic"
+)
+
+// Metrics counts Do calls (TotalRequests), retried attempts (TotalRetries)
+// and Do calls that returned an error (TotalFailures). The counters are
+// updated atomically; use Snapshot to read them while the Client is in use.
+type Metrics struct {
+	TotalRequests int64
+	TotalRetries  int64
+	TotalFailures int64
+}
+
+func (m *Metrics) Snapshot() Metrics {
+	return Metrics{
+		TotalRequests: atomic.LoadInt64(&m.TotalRequests),
+		TotalRetries:  atomic.LoadInt64(&m.TotalRetries),
+		TotalFailures: atomic.LoadInt64(&m.TotalFailures),
+	}
+}
+
+func (m *Metrics) Reset() {
+	atomic.StoreInt64(&m.TotalRequests, 0)
+	atomic.StoreInt64(&m.TotalRetries, 0)
+	atomic.StoreInt64(&m.TotalFailures, 0)
+}
@@ -0,0 +1,71 @@
+package httpext
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+)
+
+// Middleware modifies a request before Do sends it. It runs once per Do
+// call, not once per attempt.
+type Middleware func(req *Request) error
+
+// RequestHook modifies a request right before each attempt is sent, with
+// the body already rewound. Use it for anything that must be recomputed per
+// attempt, such as timestamps and signatures. An error aborts Do.
+type RequestHook func(req *Request) error
+
+// Use appends mw to the middleware run by Do for this request. Request
+// middleware runs before Client.Middleware, so client-wide middleware such as
+// signing sees the final body and headers.
+func (r *Request) Use(mw ...Middleware) *Request {
+	r.middleware = append(r.middleware, mw...)
+	return r
+}
+
+func (c *Client) applyMiddleware(req *Request) error {
+	for _, mw := range req.middleware {
+		if err := mw(req); err != nil {
+			return err
+		}
+	}
+	for _, mw := range c.Middleware {
+		if err := mw(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncodeJSON replaces the request body with v encoded as JSON.
+func EncodeJSON(v interface{}) Middleware {
+	return func(req *Request) error {
+		body, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		req.setBody(bytes.NewReader(body), int64(len(body)))
+		req.SetContentType(jsonContentType)
+		return nil
+	}
+}
+
+// EncodeForm replaces the request body with URL-encoded values.
+func EncodeForm(values url.Values) Middleware {
+	return func(req *Request) error {
+		body := values.Encode()
+		req.setBody(strings.NewReader(body), int64(len(body)))
+		req.SetContentType("application/x-www-form-urlencoded")
+		return nil
+	}
+}
+
+func (r *Request) setBody(body io.ReadSeeker, length int64) {
+	r.body = body
+	r.Request.Body = ioutil.NopCloser(body)
+	r.Request.GetBody = r.getBody
+	r.ContentLength = length
+}
@@ -0,0 +1,87 @@
+package httpext
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var ErrMockExhausted = errors.New("mock transport: no response enqueued")
+
+// MockTransport is an http.RoundTripper that replays enqueued responses in
+// order, for testing code built on Client without a server. Combined with a
+// fake Clock it makes backoff tests deterministic.
+type MockTransport struct {
+	mu        sync.Mutex
+	responses []mockResponse
+	requests  []*http.Request
+}
+
+type mockResponse struct {
+	statusCode int
+	header     http.Header
+	body       string
+}
+
+func NewMockTransport() *MockTransport {
+	return &MockTransport{}
+}
+
+func (m *MockTransport) Enqueue(statusCode int) {
+	m.EnqueueResponse(statusCode, nil, "")
+}
+
+// EnqueueWithRetryAfter enqueues a response whose Retry-After header asks
+// for retryAfterSeconds, e.g. to exercise RetryAfterBackoff.
+func (m *MockTransport) EnqueueWithRetryAfter(statusCode int, retryAfterSeconds int) {
+	header := http.Header{"Retry-After": {strconv.Itoa(retryAfterSeconds)}}
+	m.EnqueueResponse(statusCode, header, "")
+}
+
+func (m *MockTransport) EnqueueResponse(statusCode int, header http.Header, body string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses = append(m.responses, mockResponse{statusCode: statusCode, header: header, body: body})
+}
+
+// Requests returns the requests received so far.
+func (m *MockTransport) Requests() []*http.Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*http.Request(nil), m.requests...)
+}
+
+func (m *MockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests = append(m.requests, req)
+	// A RoundTripper must close the request body, even on error.
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	if len(m.responses) == 0 {
+		return nil, ErrMockExhausted
+	}
+	next := m.responses[0]
+	m.responses = m.responses[1:]
+
+	header := next.header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(next.statusCode) + " " + http.StatusText(next.statusCode),
+		StatusCode:    next.statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(next.body)),
+		ContentLength: int64(len(next.body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,84 @@
+package httpext
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type closeRecorder struct {
+	*strings.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestMockTransportClosesBodyWhenExhausted(t *testing.T) {
+	body := &closeRecorder{Reader: strings.NewReader("hello")}
+	req, err := http.NewRequest("POST", "http://example.test/", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewMockTransport().RoundTrip(req); !errors.Is(err, ErrMockExhausted) {
+		t.Fatalf("got error %v, want ErrMockExhausted", err)
+	}
+	if !body.closed {
+		t.Error("request body was not closed")
+	}
+}
+
+// recordingClock records the sleeps it is asked for instead of sleeping.
+type recordingClock struct {
+	sleeps []time.Duration
+}
+
+func (c *recordingClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c *recordingClock) Sleep(d time.Duration) {
+	c.sleeps = append(c.sleeps, d)
+}
+
+func TestMockTransportRetryAfterWithFakeClock(t *testing.T) {
+	mock := NewMockTransport()
+	mock.EnqueueWithRetryAfter(http.StatusServiceUnavailable, 2)
+	mock.EnqueueWithRetryAfter(http.StatusTooManyRequests, 3)
+	mock.Enqueue(http.StatusOK)
+
+	clock := &recordingClock{}
+	c := NewClient(&http.Client{Transport: mock})
+	c.Clock = clock
+	c.Backoff = RetryAfterBackoff
+	c.RetryWaitMax = time.Minute
+
+	resp, err := c.Get("http://example.test/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	want := []time.Duration{2 * time.Second, 3 * time.Second}
+	if len(clock.sleeps) != len(want) {
+		t.Fatalf("slept %v, want %v", clock.sleeps, want)
+	}
+	var total time.Duration
+	for i, d := range clock.sleeps {
+		if d != want[i] {
+			t.Errorf("sleep %d = %s, want %s", i, d, want[i])
+		}
+		total += d
+	}
+	if total != 5*time.Second {
+		t.Errorf("total sleep %s, want 5s", total)
+	}
+	if got := len(mock.Requests()); got != 3 {
+		t.Errorf("transport got %d requests, want 3", got)
+	}
+}
@@ -0,0 +1,45 @@
+package httpext
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// preflight probes the target of req with HEAD. done is true when req must
+// not be sent, in which case resp and err are what Do returns. Servers that
+// do not implement HEAD for the resource (405, 501) do not stop the request.
+func (c *Client) preflight(req *Request) (resp *http.Response, done bool, err error) {
+	body, ok := req.body.(*bytes.Reader)
+	if !ok || body.Size() == 0 {
+		return nil, false, nil
+	}
+
+	head, err := NewRequest("HEAD", req.URL.String(), nil)
+	if err != nil {
+		return nil, true, err
+	}
+	head.Request = head.Request.WithContext(req.Context())
+	head.Header = req.Header.Clone()
+	head.Header.Del("Content-Type")
+	head.Host = req.Host
+	head.Timeout = req.Timeout
+
+	// The probe skips the response checks of do: a HEAD response has no
+	// body to verify or trailers to capture.
+	ctx, cancel := c.requestContext(head)
+	resp, err = c.retry(ctx, head)
+	if err != nil {
+		cancel()
+		return nil, true, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+
+	switch {
+	case resp.StatusCode < 400,
+		resp.StatusCode == http.StatusMethodNotAllowed,
+		resp.StatusCode == http.StatusNotImplemented:
+		resp.Body.Close()
+		return nil, false, nil
+	}
+	return resp, true, nil
+}
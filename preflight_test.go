@@ -0,0 +1,40 @@
+package httpext
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPreflightSkipsResponseVerification(t *testing.T) {
+	secret := []byte("secret")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			return
+		}
+		ioutil.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte("stored"))
+		w.Header().Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+		w.Write([]byte("stored"))
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv, 0)
+	c.PreflightHEAD = true
+	c.ResponseSignatureVerifier = HMACResponseVerifier(secret, "X-Signature")
+
+	resp, err := c.Post(srv.URL, "text/plain", bytes.NewReader([]byte("upload")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status %d, want 200", resp.StatusCode)
+	}
+}
@@ -0,0 +1,104 @@
+package httpext
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// RetryProxy is an http.Handler that forwards every incoming request to
+// Target through Client, so that upstream calls are retried. Incoming bodies
+// are buffered in memory to make them replayable. The request path is
+// appended to Target's path and the query strings are merged.
+type RetryProxy struct {
+	Client *Client
+	Target *url.URL
+}
+
+func (p *RetryProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := p.outgoingRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	header := w.Header()
+	for key, values := range resp.Header {
+		header[key] = values
+	}
+	removeHopByHopHeaders(header)
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+func (p *RetryProxy) outgoingRequest(r *http.Request) (*Request, error) {
+	var body io.ReadSeeker
+	if r.Body != nil && r.Body != http.NoBody {
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	target := *p.Target
+	target.Path = joinURLPath(p.Target.Path, r.URL.Path)
+	target.RawPath = ""
+	switch {
+	case p.Target.RawQuery == "":
+		target.RawQuery = r.URL.RawQuery
+	case r.URL.RawQuery != "":
+		target.RawQuery = p.Target.RawQuery + "&" + r.URL.RawQuery
+	}
+
+	req, err := NewRequest(r.Method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Request = req.Request.WithContext(r.Context())
+	req.Header = r.Header.Clone()
+	removeHopByHopHeaders(req.Header)
+	return req, nil
+}
+
+func removeHopByHopHeaders(header http.Header) {
+	for _, field := range strings.Split(header.Get("Connection"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			header.Del(field)
+		}
+	}
+	for _, key := range hopByHopHeaders {
+		header.Del(key)
+	}
+}
+
+func joinURLPath(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	}
+	return strings.TrimSuffix(a, "/") + "/" + strings.TrimPrefix(b, "/")
+}
@@ -0,0 +1,58 @@
+package httpext
+
+import (
+	"net/url"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// RedactedURL returns u with the values of the given query parameters
+// replaced by [REDACTED]. The rest of the query is kept as is.
+func RedactedURL(u *url.URL, params []string) string {
+	if len(params) == 0 || u.RawQuery == "" {
+		return u.String()
+	}
+
+	pairs := strings.Split(u.RawQuery, "&")
+	for i, pair := range pairs {
+		key := pair
+		if j := strings.IndexByte(pair, '='); j >= 0 {
+			key = pair[:j]
+		}
+		if name, err := url.QueryUnescape(key); err == nil && containsString(params, name) {
+			pairs[i] = key + "=" + redacted
+		}
+	}
+
+	dup := *u
+	dup.RawQuery = strings.Join(pairs, "&")
+	return dup.String()
+}
+
+func (c *Client) redactURL(u *url.URL) string {
+	return RedactedURL(u, c.SanitizeQueryParams)
+}
+
+// redactError hides sanitized query parameters in the URL that net/http
+// puts into its errors.
+func (c *Client) redactError(err error) error {
+	if len(c.SanitizeQueryParams) == 0 {
+		return err
+	}
+	if urlErr, ok := err.(*url.Error); ok {
+		if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+			urlErr.URL = c.redactURL(u)
+		}
+	}
+	return err
+}
+
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package httpext
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+)
+
+// bufferedBody is a response body held in memory that can be handed out
+// several times through copyBufferedResponse.
+type bufferedBody struct {
+	*bytes.Reader
+	data []byte
+}
+
+func (b *bufferedBody) Close() error {
+	return nil
+}
+
+// bufferResponse reads the whole body of resp into memory and closes it.
+func bufferResponse(resp *http.Response) error {
+	if _, ok := resp.Body.(*bufferedBody); ok {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	resp.Body = &bufferedBody{Reader: bytes.NewReader(body), data: body}
+	return nil
+}
+
+// copyBufferedResponse returns a shallow copy of resp with its own reader
+// over the buffered body. Responses without a buffered body are returned
+// as is.
+func copyBufferedResponse(resp *http.Response) *http.Response {
+	body, ok := resp.Body.(*bufferedBody)
+	if !ok {
+		return resp
+	}
+
+	dup := *resp
+	dup.Header = resp.Header.Clone()
+	dup.Body = &bufferedBody{Reader: bytes.NewReader(body.data), data: body.data}
+	return &dup
+}
@@ -0,0 +1,46 @@
+package httpext
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseRetryAfter parses a Retry-After header value given either as a number
+// of seconds or as an HTTP-date (RFC 7231, section 7.1.3) and returns how long
+// to wait from now. Dates in the past yield zero.
+func ParseRetryAfter(header string) (time.Duration, error) {
+	header = strings.TrimSpace(header)
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0, fmt.Errorf("invalid Retry-After %q: negative delay", header)
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	at, err := http.ParseTime(header)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Retry-After %q", header)
+	}
+	if wait := time.Until(at); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+// RetryAfterBackoff waits as long as the Retry-After header of a 429 or 503
+// response asks for, capped at max, and falls back to DefaultBackoff
+// otherwise.
+func RetryAfterBackoff(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+	if resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+		if wait, err := ParseRetryAfter(resp.Header.Get("Retry-After")); err == nil {
+			if wait > max {
+				wait = max
+			}
+			return wait
+		}
+	}
+	return DefaultBackoff(min, max, attemptNum, resp)
+}
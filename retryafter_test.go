@@ -0,0 +1,22 @@
+package httpext
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryAfterBackoffIsCappedAtMax(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Header:     http.Header{"Retry-After": {"7200"}},
+	}
+	if got := RetryAfterBackoff(50*time.Millisecond, 200*time.Millisecond, 0, resp); got != 200*time.Millisecond {
+		t.Errorf("got %s, want the 200ms cap", got)
+	}
+
+	resp.Header.Set("Retry-After", "0")
+	if got := RetryAfterBackoff(50*time.Millisecond, 200*time.Millisecond, 0, resp); got != 0 {
+		t.Errorf("got %s, want 0 for Retry-After: 0", got)
+	}
+}
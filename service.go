@@ -0,0 +1,38 @@
+package httpext
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+const (
+	serviceRetriesMax        = 10
+	servicePerAttemptTimeout = 2 * time.Second
+)
+
+// NewClientForService returns a Client tuned for calls between internal
+// services: a short per-attempt timeout, more retries, retries for
+// idempotent methods only, X-Correlation-ID propagation and a User-Agent
+// naming the calling service.
+func NewClientForService(name string, opts ...Option) *Client {
+	defaults := func(c *Client) {
+		c.RetriesMax = serviceRetriesMax
+		c.PerAttemptTimeout = servicePerAttemptTimeout
+		c.RetryIdempotentOnly = true
+		c.CorrelationIDHeader = "X-Correlation-ID"
+		c.UserAgent = name + " httpext"
+	}
+	return NewClient(&http.Client{}, append([]Option{defaults}, opts...)...)
+}
+
+type correlationIDKey struct{}
+
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+func CorrelationID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(correlationIDKey{}).(string)
+	return id, ok
+}
@@ -0,0 +1,43 @@
+package httpext
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrShuttingDown = errors.New("client is shutting down")
+
+// GracefulShutdown makes every new Do call fail with ErrShuttingDown, stops
+// the health check and waits until the calls already in flight, retries
+// included, return or ctx is done.
+func (c *Client) GracefulShutdown(ctx context.Context) error {
+	c.shutdownMu.Lock()
+	c.shuttingDown = true
+	c.shutdownMu.Unlock()
+	c.StopHealthCheck()
+
+	done := make(chan struct{})
+	go func() {
+		c.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// enter registers a Do call unless the Client is shutting down.
+func (c *Client) enter() bool {
+	c.shutdownMu.Lock()
+	defer c.shutdownMu.Unlock()
+
+	if c.shuttingDown {
+		return false
+	}
+	c.inFlight.Add(1)
+	return true
+}
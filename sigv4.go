@@ -0,0 +1,193 @@
+package httpext
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat = "20060102T150405Z"
+	sigV4DateFormat = "20060102"
+)
+
+type AWSSigV4Config struct {
+	Region       string
+	Service      string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// WithAWSSigV4 signs every attempt with AWS Signature Version 4. The host,
+// Content-Type and X-Amz-* headers are signed; the payload hash is computed
+// over the whole request body.
+func WithAWSSigV4(cfg AWSSigV4Config) Option {
+	return func(c *Client) {
+		c.RequestHooks = append(c.RequestHooks, func(req *Request) error {
+			return signSigV4(req, cfg, c.clock().Now())
+		})
+	}
+}
+
+func signSigV4(req *Request, cfg AWSSigV4Config, now time.Time) error {
+	body, err := peekBody(req, -1)
+	if err != nil {
+		return err
+	}
+
+	now = now.UTC()
+	amzDate := now.Format(sigV4TimeFormat)
+	scope := strings.Join([]string{now.Format(sigV4DateFormat), cfg.Region, cfg.Service, "aws4_request"}, "/")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Del("Authorization")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", cfg.SessionToken)
+	}
+	if cfg.Service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	canonicalHeaders, signedHeaders := sigV4Headers(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		sigV4URI(req.URL, cfg.Service),
+		sigV4Query(req.URL),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+cfg.SecretKey), now.Format(sigV4DateFormat))
+	for _, part := range []string{cfg.Region, cfg.Service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", sigV4Algorithm+
+		" Credential="+cfg.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+
+		", Signature="+signature)
+	return nil
+}
+
+// sigV4Headers returns the canonical headers block, each entry terminated by
+// a newline, and the semicolon-separated list of signed header names.
+func sigV4Headers(req *Request) (string, string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	values := map[string]string{"host": host}
+	for key, vals := range req.Header {
+		name := strings.ToLower(key)
+		if name != "content-type" && !strings.HasPrefix(name, "x-amz-") {
+			continue
+		}
+		trimmed := make([]string, len(vals))
+		for i, v := range vals {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		values[name] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name)
+		canonical.WriteString(":")
+		canonical.WriteString(values[name])
+		canonical.WriteString("\n")
+	}
+	return canonical.String(), strings.Join(names, ";")
+}
+
+// sigV4URI builds the canonical URI from the path as net/http sends it. S3
+// signs that path as is; the other services encode each segment once more,
+// as the AWS documentation requires.
+func sigV4URI(u *url.URL, service string) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	if service == "s3" {
+		return path
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = sigV4Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func sigV4Query(u *url.URL) string {
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	escaped := make(map[string]string, len(query))
+	for key := range query {
+		escapedKey := sigV4Escape(key)
+		keys = append(keys, escapedKey)
+		escaped[escapedKey] = key
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		values := make([]string, 0, len(query[escaped[key]]))
+		for _, value := range query[escaped[key]] {
+			values = append(values, sigV4Escape(value))
+		}
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, key+"="+value)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// sigV4Escape percent-encodes everything but the RFC 3986 unreserved
+// characters.
+func sigV4Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if 'A' <= ch && ch <= 'Z' || 'a' <= ch && ch <= 'z' || '0' <= ch && ch <= '9' ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' {
+			b.WriteByte(ch)
+			continue
+		}
+		b.WriteString("%")
+		b.WriteString(strings.ToUpper(hex.EncodeToString([]byte{ch})))
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
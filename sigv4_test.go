@@ -0,0 +1,62 @@
+package httpext
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// Vectors from the AWS Signature Version 4 test suite.
+func TestSignSigV4TestSuite(t *testing.T) {
+	cfg := AWSSigV4Config{
+		Region:    "us-east-1",
+		Service:   "service",
+		AccessKey: "AKIDEXAMPLE",
+		SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		url       string
+		signature string
+	}{
+		{"get-vanilla", "https://example.amazonaws.com/", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-unreserved", "https://example.amazonaws.com/-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz", "07ef7494c76fa4850883e2b006601f940f8a34d404d0cfa977f52a65bbf5f24f"},
+		{"get-vanilla-query-order-key-case", "https://example.amazonaws.com/?Param2=value2&Param1=value1", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+	}
+	for _, tt := range tests {
+		req, err := NewRequest("GET", tt.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := signSigV4(req, cfg, now); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+			"SignedHeaders=host;x-amz-date, Signature=" + tt.signature
+		if got := req.Header.Get("Authorization"); got != want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, got, want)
+		}
+	}
+}
+
+func TestSigV4URIUsesEscapedPath(t *testing.T) {
+	tests := []struct {
+		path, service, want string
+	}{
+		{"/a!b(c)*", "service", "/a%21b%28c%29%2A"},
+		{"/a%20b/c", "service", "/a%2520b/c"},
+		{"/a%20b/c", "s3", "/a%20b/c"},
+		{"", "service", "/"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse("https://example.amazonaws.com" + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sigV4URI(u, tt.service); got != tt.want {
+			t.Errorf("sigV4URI(%q, %s) = %q, want %q", tt.path, tt.service, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package httpext
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// ResponseMetadata holds what Do learns about a response beyond its
+// headers and body.
+type ResponseMetadata struct {
+	Trailer http.Header
+}
+
+// DoWithMetadata is Do returning the response trailers alongside the
+// response. They are only complete when CaptureTrailers is set; otherwise
+// they become available in resp.Trailer once the body has been read.
+func (c *Client) DoWithMetadata(req *Request) (*http.Response, *ResponseMetadata, error) {
+	resp, err := c.Do(req)
+	if resp == nil {
+		return nil, nil, err
+	}
+	return resp, &ResponseMetadata{Trailer: resp.Trailer.Clone()}, err
+}
+
+// captureTrailers reads the body of resp to EOF, which fills resp.Trailer,
+// and hands it back to the caller through a buffered body.
+func captureTrailers(resp *http.Response) (*http.Response, error) {
+	body, err := Consume(resp)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &bufferedBody{Reader: bytes.NewReader(body), data: body}
+	return resp, nil
+}
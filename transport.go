@@ -0,0 +1,79 @@
+package httpext
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+const defaultKeepAlive = 30 * time.Second
+
+// httpClient returns HTTPClient, or a copy of it with a cloned transport
+// when some transport setting of the Client is in use. The copy is built
+// once, on the first request.
+func (c *Client) httpClient() *http.Client {
+	if !c.hasTransportSettings() {
+		return c.HTTPClient
+	}
+	c.transportOnce.Do(func() {
+		c.derivedClient = c.deriveHTTPClient()
+	})
+	return c.derivedClient
+}
+
+func (c *Client) hasTransportSettings() bool {
+	return c.ConnectTimeout > 0 || c.TLSHandshakeTimeout > 0 || c.ResponseHeaderTimeout > 0 ||
+		c.DisableCompression
+}
+
+// deriveHTTPClient applies the transport settings to a clone of the
+// HTTPClient transport. Transports other than *http.Transport cannot be
+// configured and are used as is.
+func (c *Client) deriveHTTPClient() *http.Client {
+	base := c.HTTPClient
+	if base == nil {
+		base = http.DefaultClient
+	}
+
+	var transport *http.Transport
+	switch t := base.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return base
+	}
+
+	if c.ConnectTimeout > 0 {
+		transport.DialContext = dialWithTimeout(transport.DialContext, c.ConnectTimeout)
+	}
+	if c.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = c.TLSHandshakeTimeout
+	}
+	if c.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = c.ResponseHeaderTimeout
+	}
+	if c.DisableCompression {
+		transport.DisableCompression = true
+	}
+
+	derived := *base
+	derived.Transport = transport
+	return &derived
+}
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func dialWithTimeout(dial dialFunc, timeout time.Duration) dialFunc {
+	if dial == nil {
+		dialer := &net.Dialer{Timeout: timeout, KeepAlive: defaultKeepAlive}
+		return dialer.DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return dial(ctx, network, addr)
+	}
+}
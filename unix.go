@@ -0,0 +1,20 @@
+package httpext
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// NewUnixSocketClient returns a Client that sends every request over the Unix
+// domain socket at socketPath. Following the Docker SDK convention, the host
+// part of request URLs is a placeholder, e.g. http://unix/containers/json.
+func NewUnixSocketClient(socketPath string, opts ...Option) *Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socketPath)
+		},
+	}
+	return NewClient(&http.Client{Transport: transport}, opts...)
+}
@@ -0,0 +1,61 @@
+package httpext
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var ErrSignatureMismatch = errors.New("response signature mismatch")
+
+// ResponseVerificationError tells a response rejected by
+// Client.ResponseSignatureVerifier apart from transport failures.
+type ResponseVerificationError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *ResponseVerificationError) Error() string {
+	return "response verification failed: " + e.Err.Error()
+}
+
+func (e *ResponseVerificationError) Unwrap() error {
+	return e.Err
+}
+
+// verifyResponse reads the body of resp for c.ResponseSignatureVerifier and
+// hands it back to the caller through a buffered body.
+func (c *Client) verifyResponse(resp *http.Response) (*http.Response, error) {
+	body, err := Consume(resp)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.ResponseSignatureVerifier(resp, body); err != nil {
+		return nil, &ResponseVerificationError{StatusCode: resp.StatusCode, Err: err}
+	}
+	resp.Body = &bufferedBody{Reader: bytes.NewReader(body), data: body}
+	return resp, nil
+}
+
+// HMACResponseVerifier checks that headerName holds the hex-encoded
+// HMAC-SHA256 of the body keyed with secret. A "sha256=" prefix is accepted.
+func HMACResponseVerifier(secret []byte, headerName string) func(resp *http.Response, body []byte) error {
+	return func(resp *http.Response, body []byte) error {
+		signature := strings.TrimPrefix(resp.Header.Get(headerName), "sha256=")
+		got, err := hex.DecodeString(signature)
+		if err != nil || len(got) == 0 {
+			return ErrSignatureMismatch
+		}
+
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(body)
+		if !hmac.Equal(got, mac.Sum(nil)) {
+			return ErrSignatureMismatch
+		}
+		return nil
+	}
+}
@@ -0,0 +1,35 @@
+package httpext
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+)
+
+const webhookSignatureHeader = "X-Hub-Signature-256"
+
+// SendWebhook POSTs event as JSON to url, signed with the hex-encoded
+// HMAC-SHA256 of the payload keyed with secret in X-Hub-Signature-256
+// ("sha256=<hex>"), the scheme HMACResponseVerifier checks on the way back.
+func (c *Client) SendWebhook(ctx context.Context, url string, event interface{}, secret []byte) (*http.Response, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Request = req.Request.WithContext(ctx)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	req.Header.Set("Content-Type", jsonContentType)
+	req.Header.Set(webhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return c.Do(req)
+}